@@ -0,0 +1,531 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	aptly "raptly/pkg/rest-aptly"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// spec is the desired state of the server, objects not in the spec are not touched
+type spec struct {
+	Repos     []repoSpec     `yaml:"repos"`
+	Snapshots []snapshotSpec `yaml:"snapshots"`
+	Publishes []publishSpec  `yaml:"publishes"`
+}
+
+type repoSpec struct {
+	Name         string `yaml:"name"`
+	Comment      string `yaml:"comment"`
+	Distribution string `yaml:"distribution"`
+	Component    string `yaml:"component"`
+}
+
+// snapshotSpec snapshot which has to exist, the source is only used to create it
+type snapshotSpec struct {
+	Name        string `yaml:"name"`
+	Description string `yaml:"description"`
+	// create from the local repository or mirror, empty snapshot if none is set
+	FromRepo   string `yaml:"from-repo"`
+	FromMirror string `yaml:"from-mirror"`
+}
+
+type publishSpec struct {
+	// [<storage>:]<prefix>
+	Prefix       string `yaml:"prefix"`
+	Distribution string `yaml:"distribution"`
+	// local or snapshot
+	SourceKind    string       `yaml:"source-kind"`
+	Sources       []sourceSpec `yaml:"sources"`
+	Architectures []string     `yaml:"architectures"`
+	Label         string       `yaml:"label"`
+	Origin        string       `yaml:"origin"`
+	Signing       signingSpec  `yaml:"signing"`
+}
+
+type sourceSpec struct {
+	Component string `yaml:"component"`
+	Name      string `yaml:"name"`
+}
+
+// signingSpec like the signing flags, passphrases are only read from files
+type signingSpec struct {
+	Skip                 bool   `yaml:"skip"`
+	GpgKey               string `yaml:"gpg-key"`
+	PassphraseFile       string `yaml:"passphrase-file"`
+	RemotePassphraseFile string `yaml:"remote-passphrase-file"`
+}
+
+func (s signingSpec) options() (aptly.PublishSigningOptions, error) {
+	cmd := signingCommands{
+		Skip:           s.Skip,
+		GpgKey:         s.GpgKey,
+		PassFile:       s.PassphraseFile,
+		RemotePassFile: s.RemotePassphraseFile,
+	}
+	return cmd.MakeSigningOptions()
+}
+
+// name e.g. s3:bucket:debian/bookworm
+func (p *publishSpec) name() string {
+	return aptly.ParsePublishTarget(p.Prefix).String() + "/" + p.Distribution
+}
+
+// loadSpec read and check the spec file
+func loadSpec(path string) (*spec, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	s := &spec{}
+	decoder := yaml.NewDecoder(f)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(s); err != nil {
+		return nil, fmt.Errorf("invalid spec '%s': %w", path, err)
+	}
+	if err := s.validate(); err != nil {
+		return nil, fmt.Errorf("invalid spec '%s': %w", path, err)
+	}
+	return s, nil
+}
+
+func (s *spec) validate() error {
+	errs := []error{}
+	repos := make(map[string]bool)
+	for _, repo := range s.Repos {
+		if repo.Name == "" {
+			errs = append(errs, errors.New("repo without name"))
+		} else if repos[repo.Name] {
+			errs = append(errs, fmt.Errorf("duplicate repo '%s'", repo.Name))
+		}
+		repos[repo.Name] = true
+	}
+
+	snapshots := make(map[string]bool)
+	for _, snap := range s.Snapshots {
+		if snap.Name == "" {
+			errs = append(errs, errors.New("snapshot without name"))
+		} else if snapshots[snap.Name] {
+			errs = append(errs, fmt.Errorf("duplicate snapshot '%s'", snap.Name))
+		}
+		snapshots[snap.Name] = true
+		if snap.FromRepo != "" && snap.FromMirror != "" {
+			errs = append(errs, fmt.Errorf("snapshot '%s': from-repo and from-mirror can not be combined", snap.Name))
+		}
+	}
+
+	publishes := make(map[string]bool)
+	for _, publish := range s.Publishes {
+		name := publish.name()
+		if publish.Distribution == "" {
+			errs = append(errs, fmt.Errorf("publish '%s': distribution is required", publish.Prefix))
+		} else if publishes[name] {
+			errs = append(errs, fmt.Errorf("duplicate publish '%s'", name))
+		}
+		publishes[name] = true
+		if publish.SourceKind != "local" && publish.SourceKind != "snapshot" {
+			errs = append(errs, fmt.Errorf("publish '%s': source-kind must be local or snapshot", name))
+		}
+		if len(publish.Sources) == 0 {
+			errs = append(errs, fmt.Errorf("publish '%s': minimum one source is required", name))
+		}
+		components := make(map[string]bool)
+		for _, src := range publish.Sources {
+			if src.Component == "" || src.Name == "" {
+				errs = append(errs, fmt.Errorf("publish '%s': component and name are required for every source", name))
+			} else if components[src.Component] {
+				errs = append(errs, fmt.Errorf("publish '%s': duplicate component '%s'", name, src.Component))
+			}
+			components[src.Component] = true
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// actions of changes
+const (
+	actionCreate   = "create"
+	actionUpdate   = "update"
+	actionConflict = "conflict"
+)
+
+// change is one difference between spec and server
+type change struct {
+	// create, update or conflict
+	Action string
+	// repo, snapshot or publish
+	Kind string
+	Name string
+	// changed values, for conflicts why the change can not be applied
+	Details []string `json:",omitempty"`
+
+	apply func() error
+}
+
+func (c *change) printText() {
+	marker := map[string]string{actionCreate: "+", actionUpdate: "~", actionConflict: "!"}[c.Action]
+	fmt.Printf("%s %s %s [%s]\n", marker, c.Action, c.Kind, c.Name)
+	for _, detail := range c.Details {
+		fmt.Printf("    %s\n", detail)
+	}
+}
+
+// planChanges compare spec and server, the changes are in the order they have to be applied
+func planChanges(client *aptly.Client, s *spec) ([]change, error) {
+	changes := []change{}
+
+	repos, err := client.ReposList()
+	if err != nil {
+		return nil, err
+	}
+	for _, repo := range s.Repos {
+		changes = append(changes, planRepo(client, repo, repos)...)
+	}
+
+	snapshots, err := client.SnapshotList()
+	if err != nil {
+		return nil, err
+	}
+	for _, snap := range s.Snapshots {
+		changes = append(changes, planSnapshot(client, snap, snapshots)...)
+	}
+
+	lists, err := client.PublishList()
+	if err != nil {
+		return nil, err
+	}
+	for _, publish := range s.Publishes {
+		changes = append(changes, planPublish(client, publish, lists)...)
+	}
+	return changes, nil
+}
+
+// diffValue describe a changed value, empty values of the spec are not compared
+func diffValue(details []string, field string, current string, desired string) []string {
+	if desired != "" && desired != current {
+		details = append(details, fmt.Sprintf("%s: '%s' -> '%s'", field, current, desired))
+	}
+	return details
+}
+
+func planRepo(client *aptly.Client, repo repoSpec, repos []aptly.LocalRepo) []change {
+	i := slices.IndexFunc(repos, func(r aptly.LocalRepo) bool { return r.Name == repo.Name })
+	if i == -1 {
+		return []change{{
+			Action: actionCreate,
+			Kind:   "repo",
+			Name:   repo.Name,
+			apply: func() error {
+				_, err := client.ReposCreate(repo.Name, aptly.RepoCreateOptions{
+					Comment:             repo.Comment,
+					DefaultComponent:    repo.Component,
+					DefaultDistribution: repo.Distribution,
+				})
+				return err
+			},
+		}}
+	}
+
+	current := repos[i]
+	details := diffValue(nil, "comment", current.Comment, repo.Comment)
+	details = diffValue(details, "distribution", current.DefaultDistribution, repo.Distribution)
+	details = diffValue(details, "component", current.DefaultComponent, repo.Component)
+	if len(details) == 0 {
+		return nil
+	}
+	return []change{{
+		Action:  actionUpdate,
+		Kind:    "repo",
+		Name:    repo.Name,
+		Details: details,
+		apply: func() error {
+			_, err := client.ReposEdit(repo.Name, aptly.RepoUpdateOptions{
+				Comment:             repo.Comment,
+				DefaultComponent:    repo.Component,
+				DefaultDistribution: repo.Distribution,
+			})
+			return err
+		},
+	}}
+}
+
+func planSnapshot(client *aptly.Client, snap snapshotSpec, snapshots []aptly.Snapshot) []change {
+	i := slices.IndexFunc(snapshots, func(s aptly.Snapshot) bool { return s.Name == snap.Name })
+	if i == -1 {
+		return []change{{
+			Action: actionCreate,
+			Kind:   "snapshot",
+			Name:   snap.Name,
+			apply: func() error {
+				var err error
+				switch {
+				case snap.FromRepo != "":
+					_, err = client.SnapshotFromRepo(snap.Name, snap.FromRepo, snap.Description)
+				case snap.FromMirror != "":
+					_, err = client.SnapshotFromMirror(snap.Name, snap.FromMirror, snap.Description)
+				default:
+					_, err = client.SnapshotCreate(snap.Name, aptly.SnapshotCreateOptions{Description: snap.Description})
+				}
+				return err
+			},
+		}}
+	}
+
+	// the content of snapshots never changes, only the description
+	details := diffValue(nil, "description", snapshots[i].Description, snap.Description)
+	if len(details) == 0 {
+		return nil
+	}
+	return []change{{
+		Action:  actionUpdate,
+		Kind:    "snapshot",
+		Name:    snap.Name,
+		Details: details,
+		apply: func() error {
+			_, err := client.SnapshotUpdate(snap.Name, aptly.SnapshotUpdateOptions{Description: snap.Description})
+			return err
+		},
+	}}
+}
+
+func planPublish(client *aptly.Client, publish publishSpec, lists []aptly.PublishedList) []change {
+	target := aptly.ParsePublishTarget(publish.Prefix)
+	i := slices.IndexFunc(lists, func(l aptly.PublishedList) bool {
+		return l.Target() == target && l.Distribution == publish.Distribution
+	})
+
+	components := []string{}
+	names := []string{}
+	for _, src := range publish.Sources {
+		components = append(components, src.Component)
+		names = append(names, src.Name)
+	}
+
+	if i == -1 {
+		return []change{{
+			Action: actionCreate,
+			Kind:   "publish",
+			Name:   publish.name(),
+			Details: []string{
+				fmt.Sprintf("%s {%s}", publish.SourceKind, formatSourceSpecs(publish.Sources)),
+			},
+			apply: func() error {
+				sources, err := aptly.PublishSources(components, names)
+				if err != nil {
+					return err
+				}
+				signing, err := publish.Signing.options()
+				if err != nil {
+					return err
+				}
+				opts := aptly.PublishOptions{
+					Distribution:  &publish.Distribution,
+					Architectures: publish.Architectures,
+				}
+				if publish.Label != "" {
+					opts.Label = &publish.Label
+				}
+				if publish.Origin != "" {
+					opts.Origin = &publish.Origin
+				}
+				if publish.SourceKind == "local" {
+					_, err = client.PublishRepos(sources, publish.Prefix, opts, signing)
+				} else {
+					_, err = client.PublishSnapshots(sources, publish.Prefix, opts, signing)
+				}
+				return err
+			},
+		}}
+	}
+
+	current := lists[i]
+	conflicts := []string{}
+	if current.SourceKind != publish.SourceKind {
+		conflicts = append(conflicts, fmt.Sprintf("source kind '%s' -> '%s' requires to drop and publish again", current.SourceKind, publish.SourceKind))
+	}
+	if len(publish.Architectures) > 0 && !sameElements(current.Architectures, publish.Architectures) {
+		conflicts = append(conflicts, fmt.Sprintf("architectures %v -> %v require to drop and publish again", current.Architectures, publish.Architectures))
+	}
+	for _, detail := range diffValue(diffValue(nil, "label", current.Label, publish.Label), "origin", current.Origin, publish.Origin) {
+		conflicts = append(conflicts, detail+" can not be changed, drop and publish again")
+	}
+	if len(conflicts) > 0 {
+		return []change{{Action: actionConflict, Kind: "publish", Name: publish.name(), Details: conflicts}}
+	}
+
+	details := diffSources(current.Sources, publish.Sources)
+	if len(details) == 0 {
+		return nil
+	}
+
+	switchable := canSwitchSources(current, publish)
+	return []change{{
+		Action:  actionUpdate,
+		Kind:    "publish",
+		Name:    publish.name(),
+		Details: details,
+		apply: func() error {
+			signing, err := publish.Signing.options()
+			if err != nil {
+				return err
+			}
+			if switchable {
+				snapshots, err := aptly.PublishSources(components, names)
+				if err != nil {
+					return err
+				}
+				_, err = client.PublishUpdateOrSwitch(publish.Prefix, publish.Distribution, aptly.PublishUpdateOptions{
+					Signing:   signing,
+					Snapshots: snapshots,
+				})
+				return err
+			}
+
+			// components of local repositories and new components can only be staged, requires aptly 1.6.0
+			entries := []aptly.SourceEntry{}
+			for _, src := range publish.Sources {
+				entries = append(entries, aptly.SourceEntry{Component: src.Component, Name: src.Name})
+			}
+			if _, err := client.PublishSourcesSet(publish.Prefix, publish.Distribution, entries); err != nil {
+				return err
+			}
+			_, err = client.PublishSourcesApply(publish.Prefix, publish.Distribution, aptly.PublishUpdateOptions{Signing: signing})
+			return err
+		},
+	}}
+}
+
+// canSwitchSources true if the snapshots of the publish can be switched in place,
+// other source changes have to be staged with the sources API
+func canSwitchSources(current aptly.PublishedList, publish publishSpec) bool {
+	components := []string{}
+	for _, src := range publish.Sources {
+		components = append(components, src.Component)
+	}
+	return publish.SourceKind == "snapshot" && sameElements(sourceComponents(current.Sources), components)
+}
+
+// diffSources describe added, removed and changed components
+func diffSources(current []aptly.SourceEntry, desired []sourceSpec) []string {
+	details := []string{}
+	for _, src := range desired {
+		i := slices.IndexFunc(current, func(e aptly.SourceEntry) bool { return e.Component == src.Component })
+		if i == -1 {
+			details = append(details, fmt.Sprintf("add %s: [%s]", src.Component, src.Name))
+		} else if current[i].Name != src.Name {
+			details = append(details, fmt.Sprintf("%s: [%s] -> [%s]", src.Component, current[i].Name, src.Name))
+		}
+	}
+	for _, entry := range current {
+		if !slices.ContainsFunc(desired, func(s sourceSpec) bool { return s.Component == entry.Component }) {
+			details = append(details, fmt.Sprintf("remove %s: [%s]", entry.Component, entry.Name))
+		}
+	}
+	return details
+}
+
+func sourceComponents(sources []aptly.SourceEntry) []string {
+	components := []string{}
+	for _, src := range sources {
+		components = append(components, src.Component)
+	}
+	return components
+}
+
+func formatSourceSpecs(sources []sourceSpec) string {
+	formatted := []string{}
+	for _, src := range sources {
+		formatted = append(formatted, fmt.Sprintf("%s: [%s]", src.Component, src.Name))
+	}
+	return strings.Join(formatted, ", ")
+}
+
+// sameElements true if a and b contain the same strings in any order
+func sameElements(a []string, b []string) bool {
+	return slices.Equal(slices.Sorted(slices.Values(a)), slices.Sorted(slices.Values(b)))
+}
+
+type PlanCmd struct {
+	File string `kong:"short='f',required,type='existingfile',help='YAML spec of repos, snapshots and publishes'"`
+}
+
+func (c *PlanCmd) Run(ctx *Context) error {
+	s, err := loadSpec(c.File)
+	if err != nil {
+		return err
+	}
+	changes, err := planChanges(ctx.client, s)
+	if err != nil {
+		return err
+	}
+
+	err = ctx.print(changes, func() {
+		if len(changes) == 0 {
+			fmt.Println("No changes, the server matches the spec.")
+		}
+		for _, change := range changes {
+			change.printText()
+		}
+	})
+	if err != nil {
+		return err
+	}
+	if len(changes) > 0 {
+		return exitError{fmt.Errorf("server differs from spec in %d objects", len(changes)), exitDrift}
+	}
+	return nil
+}
+
+type ApplyCmd struct {
+	File string `kong:"short='f',required,type='existingfile',help='YAML spec of repos, snapshots and publishes'"`
+}
+
+func (c *ApplyCmd) Run(ctx *Context) error {
+	s, err := loadSpec(c.File)
+	if err != nil {
+		return err
+	}
+	changes, err := planChanges(ctx.client, s)
+	if err != nil {
+		return err
+	}
+
+	conflicts := 0
+	for _, change := range changes {
+		if change.Action == actionConflict {
+			conflicts++
+		}
+	}
+	if conflicts > 0 {
+		err := ctx.print(changes, func() {
+			for _, change := range changes {
+				change.printText()
+			}
+		})
+		if err != nil {
+			return err
+		}
+		return fmt.Errorf("%d objects can not be changed to match the spec, nothing has been applied", conflicts)
+	}
+
+	for _, change := range changes {
+		ctx.printText(change.printText)
+		if err := change.apply(); err != nil {
+			return fmt.Errorf("%s %s [%s] failed: %w", change.Action, change.Kind, change.Name, err)
+		}
+	}
+
+	return ctx.print(changes, func() {
+		if len(changes) == 0 {
+			fmt.Println("No changes, the server matches the spec.")
+			return
+		}
+		fmt.Printf("Applied %d changes.\n", len(changes))
+	})
+}
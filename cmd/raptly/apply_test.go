@@ -0,0 +1,309 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	aptly "raptly/pkg/rest-aptly"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// specClientForTest client of a server with a repo, two snapshots and two publishes
+func specClientForTest(t *testing.T) *aptly.Client {
+	client := clientForTest(t)
+	httpmock.RegisterResponder(http.MethodGet, "http://host.local/api/repos",
+		newRawJSONResponder(200, `[{"Name": "testing", "Comment": "old", "DefaultDistribution": "bookworm", "DefaultComponent": "main"}]`))
+	httpmock.RegisterResponder(http.MethodGet, "http://host.local/api/snapshots",
+		newRawJSONResponder(200, `[{"Name": "s1", "Description": "first"}, {"Name": "s2"}]`))
+	httpmock.RegisterResponder(http.MethodGet, "http://host.local/api/publish",
+		newRawJSONResponder(200, `[
+			{"Prefix": "snap", "Distribution": "bookworm", "SourceKind": "snapshot", "Architectures": ["amd64", "arm64"],
+			 "Label": "label", "Sources": [{"Component": "main", "Name": "s1"}]},
+			{"Prefix": "repo", "Distribution": "bookworm", "SourceKind": "local", "Architectures": ["amd64"],
+			 "Sources": [{"Component": "main", "Name": "testing"}]}
+		]`))
+	return client
+}
+
+// applyChanges apply all changes, returns the calls changing the server
+func applyChanges(t *testing.T, changes []change) map[string]int {
+	httpmock.ZeroCallCounters()
+	for _, change := range changes {
+		require.NotNil(t, change.apply, "%s %s [%s]", change.Action, change.Kind, change.Name)
+		assert.NoError(t, change.apply())
+	}
+	calls := map[string]int{}
+	for call, count := range httpmock.GetCallCountInfo() {
+		if count > 0 && call != "GET http://host.local/api/version" {
+			calls[call] = count
+		}
+	}
+	return calls
+}
+
+func snapshotPublish(sources ...sourceSpec) publishSpec {
+	return publishSpec{
+		Prefix:        "snap",
+		Distribution:  "bookworm",
+		SourceKind:    "snapshot",
+		Sources:       sources,
+		Architectures: []string{"arm64", "amd64"},
+		Signing:       signingSpec{Skip: true},
+	}
+}
+
+func TestPlanRepos(t *testing.T) {
+	client := specClientForTest(t)
+
+	changes, err := planChanges(client, &spec{Repos: []repoSpec{
+		// empty values are not compared
+		{Name: "testing", Comment: "old"},
+		{Name: "testing", Distribution: "trixie"},
+		{Name: "new", Comment: "comment"},
+	}})
+	assert.NoError(t, err)
+	assert.Equal(t, []change{
+		{Action: actionUpdate, Kind: "repo", Name: "testing", Details: []string{"distribution: 'bookworm' -> 'trixie'"}},
+		{Action: actionCreate, Kind: "repo", Name: "new"},
+	}, withoutApply(changes))
+
+	httpmock.RegisterResponder(http.MethodPut, "http://host.local/api/repos/testing",
+		newRawJSONResponder(200, `{"Name": "testing"}`))
+	httpmock.RegisterResponder(http.MethodPost, "http://host.local/api/repos",
+		newRawJSONResponder(201, `{"Name": "new"}`))
+	assert.Equal(t, map[string]int{
+		"PUT http://host.local/api/repos/testing": 1,
+		"POST http://host.local/api/repos":        1,
+	}, applyChanges(t, changes))
+}
+
+func TestPlanSnapshots(t *testing.T) {
+	client := specClientForTest(t)
+
+	changes, err := planChanges(client, &spec{Snapshots: []snapshotSpec{
+		{Name: "s1", Description: "first", FromRepo: "testing"},
+		{Name: "s2", Description: "second"},
+		{Name: "from-repo", FromRepo: "testing"},
+		{Name: "from-mirror", FromMirror: "debian"},
+		{Name: "empty"},
+	}})
+	assert.NoError(t, err)
+	assert.Equal(t, []change{
+		{Action: actionUpdate, Kind: "snapshot", Name: "s2", Details: []string{"description: '' -> 'second'"}},
+		{Action: actionCreate, Kind: "snapshot", Name: "from-repo"},
+		{Action: actionCreate, Kind: "snapshot", Name: "from-mirror"},
+		{Action: actionCreate, Kind: "snapshot", Name: "empty"},
+	}, withoutApply(changes))
+
+	httpmock.RegisterResponder(http.MethodPut, "http://host.local/api/snapshots/s2",
+		newRawJSONResponder(200, `{"Name": "s2"}`))
+	httpmock.RegisterResponder(http.MethodPost, "http://host.local/api/repos/testing/snapshots",
+		newRawJSONResponder(201, `{"Name": "from-repo"}`))
+	httpmock.RegisterResponder(http.MethodPost, "http://host.local/api/mirrors/debian/snapshots",
+		newRawJSONResponder(201, `{"Name": "from-mirror"}`))
+	httpmock.RegisterResponder(http.MethodPost, "http://host.local/api/snapshots",
+		newRawJSONResponder(201, `{"Name": "empty"}`))
+	assert.Equal(t, map[string]int{
+		"PUT http://host.local/api/snapshots/s2":              1,
+		"POST http://host.local/api/repos/testing/snapshots":  1,
+		"POST http://host.local/api/mirrors/debian/snapshots": 1,
+		"POST http://host.local/api/snapshots":                1,
+	}, applyChanges(t, changes))
+}
+
+func TestPlanPublishNoChanges(t *testing.T) {
+	client := specClientForTest(t)
+
+	changes, err := planChanges(client, &spec{Publishes: []publishSpec{
+		// architectures in any order, label not set
+		snapshotPublish(sourceSpec{Component: "main", Name: "s1"}),
+		{Prefix: "repo", Distribution: "bookworm", SourceKind: "local", Sources: []sourceSpec{{Component: "main", Name: "testing"}}},
+	}})
+	assert.NoError(t, err)
+	assert.Empty(t, changes)
+}
+
+func TestPlanPublishCreate(t *testing.T) {
+	client := specClientForTest(t)
+
+	changes, err := planChanges(client, &spec{Publishes: []publishSpec{
+		{Prefix: "repo", Distribution: "trixie", SourceKind: "local", Sources: []sourceSpec{{Component: "main", Name: "testing"}},
+			Signing: signingSpec{Skip: true}},
+		{Prefix: "new", Distribution: "bookworm", SourceKind: "snapshot", Sources: []sourceSpec{{Component: "main", Name: "s1"}},
+			Signing: signingSpec{Skip: true}},
+	}})
+	assert.NoError(t, err)
+	assert.Equal(t, []change{
+		{Action: actionCreate, Kind: "publish", Name: "repo/trixie", Details: []string{"local {main: [testing]}"}},
+		{Action: actionCreate, Kind: "publish", Name: "new/bookworm", Details: []string{"snapshot {main: [s1]}"}},
+	}, withoutApply(changes))
+
+	httpmock.RegisterResponder(http.MethodPost, "http://host.local/api/publish/repo",
+		newRawJSONResponder(201, `{"Prefix": "repo", "Distribution": "trixie"}`))
+	httpmock.RegisterResponder(http.MethodPost, "http://host.local/api/publish/new",
+		newRawJSONResponder(201, `{"Prefix": "new", "Distribution": "bookworm"}`))
+	assert.Equal(t, map[string]int{
+		"POST http://host.local/api/publish/repo": 1,
+		"POST http://host.local/api/publish/new":  1,
+	}, applyChanges(t, changes))
+}
+
+func TestPlanPublishConflicts(t *testing.T) {
+	client := specClientForTest(t)
+
+	sourceKind := snapshotPublish(sourceSpec{Component: "main", Name: "s1"})
+	sourceKind.Prefix = "repo"
+	sourceKind.Architectures = nil
+	architectures := snapshotPublish(sourceSpec{Component: "main", Name: "s2"})
+	architectures.Architectures = []string{"amd64"}
+	label := snapshotPublish(sourceSpec{Component: "main", Name: "s2"})
+	label.Label = "other"
+	label.Origin = "origin"
+
+	for name, publish := range map[string]publishSpec{"source kind": sourceKind, "architectures": architectures, "label": label} {
+		t.Run(name, func(t *testing.T) {
+			changes, err := planChanges(client, &spec{Publishes: []publishSpec{publish}})
+			assert.NoError(t, err)
+			require.Len(t, changes, 1)
+			assert.Equal(t, actionConflict, changes[0].Action)
+			assert.Nil(t, changes[0].apply)
+		})
+	}
+
+	changes, err := planChanges(client, &spec{Publishes: []publishSpec{label}})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"label: 'label' -> 'other' can not be changed, drop and publish again",
+		"origin: '' -> 'origin' can not be changed, drop and publish again",
+	}, changes[0].Details)
+}
+
+func TestPlanPublishSwitch(t *testing.T) {
+	client := specClientForTest(t)
+
+	changes, err := planChanges(client, &spec{Publishes: []publishSpec{
+		snapshotPublish(sourceSpec{Component: "main", Name: "s2"}),
+	}})
+	assert.NoError(t, err)
+	assert.Equal(t, []change{
+		{Action: actionUpdate, Kind: "publish", Name: "snap/bookworm", Details: []string{"main: [s1] -> [s2]"}},
+	}, withoutApply(changes))
+
+	httpmock.RegisterResponder(http.MethodPut, "http://host.local/api/publish/snap/bookworm",
+		newRawJSONResponder(200, `{"Prefix": "snap", "Distribution": "bookworm"}`))
+	assert.Equal(t, map[string]int{
+		"PUT http://host.local/api/publish/snap/bookworm": 1,
+	}, applyChanges(t, changes))
+}
+
+func TestPlanPublishStaged(t *testing.T) {
+	client := specClientForTest(t)
+
+	changes, err := planChanges(client, &spec{Publishes: []publishSpec{
+		// new component
+		snapshotPublish(sourceSpec{Component: "main", Name: "s1"}, sourceSpec{Component: "contrib", Name: "s2"}),
+		// local repos
+		{Prefix: "repo", Distribution: "bookworm", SourceKind: "local", Sources: []sourceSpec{{Component: "contrib", Name: "testing"}},
+			Signing: signingSpec{Skip: true}},
+	}})
+	assert.NoError(t, err)
+	assert.Equal(t, []change{
+		{Action: actionUpdate, Kind: "publish", Name: "snap/bookworm", Details: []string{"add contrib: [s2]"}},
+		{Action: actionUpdate, Kind: "publish", Name: "repo/bookworm", Details: []string{"add contrib: [testing]", "remove main: [testing]"}},
+	}, withoutApply(changes))
+
+	for _, prefix := range []string{"snap", "repo"} {
+		httpmock.RegisterResponder(http.MethodPut, "http://host.local/api/publish/"+prefix+"/bookworm/sources",
+			newRawJSONResponder(200, `{}`))
+		httpmock.RegisterResponder(http.MethodPost, "http://host.local/api/publish/"+prefix+"/bookworm/update",
+			newRawJSONResponder(200, `{}`))
+	}
+	assert.Equal(t, map[string]int{
+		"PUT http://host.local/api/publish/snap/bookworm/sources": 1,
+		"POST http://host.local/api/publish/snap/bookworm/update": 1,
+		"PUT http://host.local/api/publish/repo/bookworm/sources": 1,
+		"POST http://host.local/api/publish/repo/bookworm/update": 1,
+	}, applyChanges(t, changes))
+}
+
+func TestSpecValidate(t *testing.T) {
+	valid := snapshotPublish(sourceSpec{Component: "main", Name: "s1"})
+	assert.NoError(t, (&spec{
+		Repos:     []repoSpec{{Name: "testing"}},
+		Snapshots: []snapshotSpec{{Name: "s1", FromRepo: "testing"}},
+		Publishes: []publishSpec{valid},
+	}).validate())
+
+	noSources := valid
+	noSources.Prefix = "other"
+	noSources.Sources = nil
+	invalid := publishSpec{Prefix: "invalid", Distribution: "bookworm", SourceKind: "repo",
+		Sources: []sourceSpec{{Component: "main", Name: "s1"}, {Component: "main", Name: "s2"}, {Name: "s3"}}}
+	err := (&spec{
+		Repos:     []repoSpec{{Name: "testing"}, {Name: "testing"}, {}},
+		Snapshots: []snapshotSpec{{Name: "s1", FromRepo: "testing", FromMirror: "debian"}, {Name: "s1"}},
+		Publishes: []publishSpec{valid, valid, noSources, invalid, {Prefix: "nodist", SourceKind: "local", Sources: valid.Sources}},
+	}).validate()
+	assert.EqualError(t, err, `duplicate repo 'testing'
+repo without name
+snapshot 's1': from-repo and from-mirror can not be combined
+duplicate snapshot 's1'
+duplicate publish 'snap/bookworm'
+publish 'other/bookworm': minimum one source is required
+publish 'invalid/bookworm': source-kind must be local or snapshot
+publish 'invalid/bookworm': duplicate component 'main'
+publish 'invalid/bookworm': component and name are required for every source
+publish 'nodist': distribution is required`)
+}
+
+func TestPlanCmdDrift(t *testing.T) {
+	ctx := &Context{client: specClientForTest(t), output: outputText}
+
+	path := filepath.Join(t.TempDir(), "aptly.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+repos:
+  - name: testing
+    comment: old
+`), 0o644))
+	assert.NoError(t, (&PlanCmd{File: path}).Run(ctx))
+
+	require.NoError(t, os.WriteFile(path, []byte(`
+repos:
+  - name: testing
+    comment: new
+`), 0o644))
+	err := (&PlanCmd{File: path}).Run(ctx)
+	var exitErr exitError
+	require.True(t, errors.As(err, &exitErr))
+	assert.Equal(t, exitDrift, exitErr.ExitCode())
+
+	// conflicts are not applied
+	require.NoError(t, os.WriteFile(path, []byte(`
+publishes:
+  - prefix: repo
+    distribution: bookworm
+    source-kind: snapshot
+    sources:
+      - component: main
+        name: s1
+`), 0o644))
+	httpmock.ZeroCallCounters()
+	err = (&ApplyCmd{File: path}).Run(ctx)
+	assert.EqualError(t, err, "1 objects can not be changed to match the spec, nothing has been applied")
+	assert.Equal(t, 0, httpmock.GetCallCountInfo()["PUT http://host.local/api/publish/repo/bookworm"])
+}
+
+// withoutApply changes without apply functions, to compare them
+func withoutApply(changes []change) []change {
+	compared := []change{}
+	for _, change := range changes {
+		change.apply = nil
+		compared = append(compared, change)
+	}
+	return compared
+}
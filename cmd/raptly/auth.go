@@ -0,0 +1,90 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	aptly "raptly/pkg/rest-aptly"
+	"strings"
+)
+
+// authenticator get the authentication of the connection settings, nil for none
+//
+// custom headers are set last and may replace the credentials
+func (f *connectionFlags) authenticator() (aptly.Authenticator, error) {
+	auths := []aptly.Authenticator{}
+
+	bearer := f.Token != nil || f.TokenCommand != ""
+	switch {
+	case f.User != nil && bearer:
+		return nil, errors.New("basic auth and bearer token can not be combined")
+	case f.Token != nil && f.TokenCommand != "":
+		return nil, errors.New("--token and --token-command can not be combined")
+	case f.User != nil:
+		if f.BasicPW == nil {
+			return nil, errors.New("basic auth username set but no password, define RAPTLY_BASIC_PASS environment variable or use --basic-pass")
+		}
+		auths = append(auths, aptly.BasicAuth(*f.User, *f.BasicPW))
+	case f.Token != nil:
+		auths = append(auths, aptly.BearerAuth(*f.Token))
+	case f.TokenCommand != "":
+		token, err := runTokenCommand(f.TokenCommand)
+		if err != nil {
+			return nil, err
+		}
+		auths = append(auths, aptly.BearerAuth(token))
+	case f.Netrc != nil && *f.Netrc:
+		auth, err := aptly.NetrcAuth(aptly.DefaultNetrcPath())
+		if err != nil {
+			return nil, err
+		}
+		auths = append(auths, auth)
+	}
+
+	if len(f.Header) > 0 {
+		headers, err := parseHeaders(f.Header)
+		if err != nil {
+			return nil, err
+		}
+		auths = append(auths, aptly.HeaderAuth(headers))
+	}
+
+	switch len(auths) {
+	case 0:
+		return nil, nil
+	case 1:
+		return auths[0], nil
+	}
+	return aptly.MultiAuth(auths...), nil
+}
+
+// runTokenCommand run command with sh and get the token from its output
+func runTokenCommand(command string) (string, error) {
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("token command failed: %w", err)
+	}
+	token := strings.TrimSpace(string(out))
+	if token == "" {
+		return "", errors.New("token command printed no token")
+	}
+	return token, nil
+}
+
+// parseHeaders parse headers in the form "Name: value"
+func parseHeaders(headers []string) (http.Header, error) {
+	parsed := http.Header{}
+	for _, header := range headers {
+		name, value, ok := strings.Cut(header, ":")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid header '%s', expected 'Name: value'", header)
+		}
+		parsed.Add(name, strings.TrimSpace(value))
+	}
+	return parsed, nil
+}
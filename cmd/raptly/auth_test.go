@@ -0,0 +1,48 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// authorization get the Authorization header the connection settings set for url
+func authorization(t *testing.T, f *connectionFlags, url string) string {
+	auth, err := f.authenticator()
+	require.NoError(t, err)
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	require.NoError(t, err)
+	if auth != nil {
+		require.NoError(t, auth.Authenticate(req))
+	}
+	return req.Header.Get("Authorization")
+}
+
+func TestApplyProfileAuth(t *testing.T) {
+	netrc := filepath.Join(t.TempDir(), "netrc")
+	require.NoError(t, os.WriteFile(netrc, []byte("machine host.local login netrc password secret\n"), 0o600))
+	t.Setenv("NETRC", netrc)
+
+	user, password, token, enabled := "profile", "pw", "token", true
+	p := &profile{User: &user, BasicPW: &password}
+
+	// auth of the profile
+	f := &connectionFlags{}
+	f.applyProfile(p)
+	assert.Equal(t, "Basic cHJvZmlsZTpwdw==", authorization(t, f, "http://host.local/api/version"))
+
+	// --netrc replaces the user of the profile
+	f = &connectionFlags{Netrc: &enabled}
+	f.applyProfile(p)
+	assert.Nil(t, f.User)
+	assert.Equal(t, "Basic bmV0cmM6c2VjcmV0", authorization(t, f, "http://host.local/api/version"))
+
+	// --token replaces netrc of the profile
+	f = &connectionFlags{Token: &token}
+	f.applyProfile(&profile{Netrc: &enabled})
+	assert.Equal(t, "Bearer token", authorization(t, f, "http://host.local/api/version"))
+}
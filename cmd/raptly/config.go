@@ -0,0 +1,259 @@
+package main
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// configFile is the content of the raptly configuration file
+type configFile struct {
+	// profile used when neither --profile nor RAPTLY_PROFILE is set
+	DefaultProfile string             `yaml:"default-profile"`
+	Profiles       map[string]profile `yaml:"profiles"`
+}
+
+// profile settings of one aptly server, unset values keep the defaults
+type profile struct {
+	Url          string            `yaml:"url"`
+	User         *string           `yaml:"user"`
+	BasicPW      *string           `yaml:"basic-pass"`
+	Token        *string           `yaml:"token"`
+	TokenCommand string            `yaml:"token-command"`
+	Headers      map[string]string `yaml:"headers"`
+	Netrc        *bool             `yaml:"netrc"`
+	Insecure     *bool             `yaml:"insecure"`
+	CACert       string            `yaml:"ca-cert"`
+	ClientCert   string            `yaml:"client-cert"`
+	ClientKey    string            `yaml:"client-key"`
+	NoProxy      *bool             `yaml:"no-proxy"`
+	Proxy        string            `yaml:"proxy"`
+	Retries      *int              `yaml:"retries"`
+	RetryWait    *time.Duration    `yaml:"retry-wait"`
+}
+
+// defaultConfigPath e.g. ~/.config/raptly/config.yaml
+func defaultConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "raptly", "config.yaml")
+}
+
+// loadConfig read the configuration file, a missing file is an empty configuration unless required
+func loadConfig(path string, required bool) (*configFile, error) {
+	conf := &configFile{}
+	if path == "" {
+		return conf, nil
+	}
+
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) && !required {
+		return conf, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	decoder := yaml.NewDecoder(f)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(conf); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("invalid config file '%s': %w", path, err)
+	}
+	if conf.DefaultProfile != "" {
+		if _, ok := conf.Profiles[conf.DefaultProfile]; !ok {
+			return nil, fmt.Errorf("invalid config file '%s': default profile '%s' not defined", path, conf.DefaultProfile)
+		}
+	}
+	return conf, nil
+}
+
+// loadConfig read the config file of --config or the default path
+func (ctx *Context) loadConfig() (*configFile, error) {
+	return loadConfig(ctx.configPath, ctx.configRequired)
+}
+
+// profileNames sorted names of all profiles
+func (c *configFile) profileNames() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// profile get the named profile, the default profile if name is empty
+//
+// returns nil without error if no profile is selected
+func (c *configFile) profile(name string) (*profile, error) {
+	if name == "" {
+		name = c.DefaultProfile
+	}
+	if name == "" {
+		return nil, nil
+	}
+	p, ok := c.Profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("profile '%s' not defined in config file", name)
+	}
+	return &p, nil
+}
+
+// validate check the settings of the profile
+func (p *profile) validate() error {
+	errs := []error{}
+	if p.Url == "" {
+		errs = append(errs, errors.New("url is required"))
+	} else if err := validateUrl(p.Url); err != nil {
+		errs = append(errs, err)
+	}
+	if p.Proxy != "" {
+		if _, err := url.Parse(p.Proxy); err != nil {
+			errs = append(errs, fmt.Errorf("invalid proxy: %w", err))
+		}
+	}
+	for _, file := range []string{p.CACert, p.ClientCert, p.ClientKey} {
+		if file == "" {
+			continue
+		}
+		if _, err := os.Stat(file); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if p.ClientKey != "" && p.ClientCert == "" {
+		errs = append(errs, errors.New("client-key set but no client-cert"))
+	}
+	if p.User != nil && p.BasicPW == nil {
+		errs = append(errs, errors.New("user set but no basic-pass"))
+	}
+	if p.User != nil && (p.Token != nil || p.TokenCommand != "") {
+		errs = append(errs, errors.New("user and token can not be combined"))
+	}
+	if p.Token != nil && p.TokenCommand != "" {
+		errs = append(errs, errors.New("token and token-command can not be combined"))
+	}
+	if p.Retries != nil && *p.Retries < 0 {
+		errs = append(errs, errors.New("retries must not be negative"))
+	}
+	return errors.Join(errs...)
+}
+
+func validateUrl(rawUrl string) error {
+	u, err := url.Parse(rawUrl)
+	if err != nil {
+		return fmt.Errorf("invalid url: %w", err)
+	}
+	switch u.Scheme {
+	case "http", "https":
+	case "unix":
+		if u.Host != "" || u.Path == "" {
+			return fmt.Errorf("invalid url '%s': expected unix:///path/to/socket", rawUrl)
+		}
+	default:
+		return fmt.Errorf("invalid url '%s': scheme must be http, https or unix", rawUrl)
+	}
+	return nil
+}
+
+type ConfigCLI struct {
+	List     ConfigListCmd     `kong:"cmd,help='List profiles of the config file'"`
+	Validate ConfigValidateCmd `kong:"cmd,help='Validate profiles of the config file'"`
+}
+
+// profileOutput profile of config list for json and yaml output, without credentials
+type profileOutput struct {
+	Name     string
+	Url      string
+	Selected bool
+}
+
+type ConfigListCmd struct{}
+
+func (c *ConfigListCmd) Run(ctx *Context) error {
+	config, err := ctx.loadConfig()
+	if err != nil {
+		return err
+	}
+	selected := cmp.Or(ctx.profile, config.DefaultProfile)
+	profiles := []profileOutput{}
+	for _, name := range config.profileNames() {
+		profiles = append(profiles, profileOutput{Name: name, Url: config.Profiles[name].Url, Selected: name == selected})
+	}
+
+	return ctx.print(profiles, func() {
+		fmt.Printf("Profiles in %s:\n", ctx.configPath)
+		for _, p := range profiles {
+			marker := " "
+			if p.Selected {
+				marker = "*"
+			}
+			fmt.Printf(" %s %s: %s\n", marker, p.Name, p.Url)
+		}
+	})
+}
+
+// profileStatus result of config validate for json and yaml output
+type profileStatus struct {
+	Name  string
+	Valid bool
+	Error string `json:"Error,omitempty"`
+}
+
+type ConfigValidateCmd struct {
+	Profiles []string `kong:"arg,optional,name='profile',help='profiles to validate, all if none given'"`
+}
+
+func (c *ConfigValidateCmd) Run(ctx *Context) error {
+	config, err := ctx.loadConfig()
+	if err != nil {
+		return err
+	}
+	names := c.Profiles
+	if len(names) == 0 {
+		names = config.profileNames()
+	}
+
+	invalid := []string{}
+	results := []profileStatus{}
+	for _, name := range names {
+		p, err := config.profile(name)
+		if err == nil {
+			err = p.validate()
+		}
+		result := profileStatus{Name: name, Valid: err == nil}
+		if err != nil {
+			invalid = append(invalid, name)
+			result.Error = strings.ReplaceAll(err.Error(), "\n", "; ")
+		}
+		results = append(results, result)
+	}
+
+	err = ctx.print(results, func() {
+		for _, result := range results {
+			if result.Valid {
+				fmt.Printf("%s: ok\n", result.Name)
+			} else {
+				fmt.Printf("%s: %s\n", result.Name, result.Error)
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+	if len(invalid) > 0 {
+		return fmt.Errorf("invalid profiles: %s", strings.Join(invalid, ", "))
+	}
+	return nil
+}
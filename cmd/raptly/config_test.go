@@ -0,0 +1,34 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadConfigMissing(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+
+	// the default path may be missing
+	config, err := loadConfig(path, false)
+	assert.NoError(t, err)
+	assert.Empty(t, config.Profiles)
+
+	_, err = loadConfig(path, true)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestConfigValidateInvalidFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte("profiles:\n  dev:\n    uri: http://aptly:8080\n"), 0o600))
+	ctx := &Context{configPath: path, configRequired: true, output: outputText}
+
+	err := (&ConfigValidateCmd{}).Run(ctx)
+	assert.ErrorContains(t, err, "invalid config file")
+	assert.ErrorContains(t, err, "field uri not found")
+
+	err = (&ConfigListCmd{}).Run(ctx)
+	assert.ErrorContains(t, err, "invalid config file")
+}
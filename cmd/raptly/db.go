@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+type DbCLI struct {
+	Cleanup dbCleanupCmd `kong:"cmd,help='cleanup DB and package pool'"`
+}
+
+type dbCleanupCmd struct {
+	DryRun  bool          `kong:"name='dry-run',help='only list unreferenced packages, computed client side, requires Aptly Server 1.6.0'"`
+	Async   bool          `kong:"name='async',help='run cleanup as task on the server and wait for it'"`
+	Timeout time.Duration `kong:"name='timeout',help='with --async give up waiting after this duration'"`
+}
+
+func (c *dbCleanupCmd) Run(ctx *Context) error {
+	if c.DryRun {
+		pkgs, err := ctx.client.DbUnreferencedPackages()
+		if err != nil {
+			return err
+		}
+
+		return ctx.print(pkgs, func() {
+			fmt.Printf("%d unreferenced packages would be deleted:\n", len(pkgs))
+			for _, pkg := range pkgs {
+				fmt.Printf("  %s\n", pkg.Key)
+			}
+		})
+	}
+
+	if c.Async {
+		task, err := ctx.client.DbCleanupAsync()
+		if err != nil {
+			return err
+		}
+		ctx.printText(func() {
+			fmt.Printf("Started task [%d] %s\n", task.ID, task.Name)
+		})
+		task, err = ctx.client.TasksWait(task.ID, c.Timeout)
+		if err != nil {
+			return err
+		}
+		return ctx.print(task, func() {
+			fmt.Println("DB cleanup finished.")
+		})
+	}
+
+	if err := ctx.client.DbCleanup(); err != nil {
+		return err
+	}
+
+	ctx.printText(func() {
+		fmt.Println("DB cleanup finished.")
+	})
+	return nil
+}
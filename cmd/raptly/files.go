@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	aptly "raptly/pkg/rest-aptly"
+	"time"
 )
 
 type FilesCLI struct {
@@ -21,11 +23,12 @@ func (c *FileListCmd) Run(ctx *Context) error {
 		return err
 	}
 
-	fmt.Println("List of directories:")
-	for _, dir := range dirs {
-		fmt.Printf(" * %s\n", dir)
-	}
-	return nil
+	return ctx.print(dirs, func() {
+		fmt.Println("List of directories:")
+		for _, dir := range dirs {
+			fmt.Printf(" * %s\n", dir)
+		}
+	})
 }
 
 type FileShowCmd struct {
@@ -38,17 +41,18 @@ func (c *FileShowCmd) Run(ctx *Context) error {
 		return err
 	}
 
-	fmt.Printf("Files in '%s':\n", c.Name)
-	for _, file := range files {
-		fmt.Printf(" * %s\n", file)
-	}
-
-	return nil
+	return ctx.print(files, func() {
+		fmt.Printf("Files in '%s':\n", c.Name)
+		for _, file := range files {
+			fmt.Printf(" * %s\n", file)
+		}
+	})
 }
 
 type FileUploadCmd struct {
-	Dir  string `kong:"arg"`
-	Path string `kong:"arg"`
+	Dir      string `kong:"arg"`
+	Path     string `kong:"arg"`
+	Parallel int    `kong:"default='1',help='upload files in up to N concurrent requests, one per file'"`
 }
 
 func (c *FileUploadCmd) Run(ctx *Context) error {
@@ -75,16 +79,17 @@ func (c *FileUploadCmd) Run(ctx *Context) error {
 		files = append(files, c.Path)
 	}
 
-	uploaded, err := ctx.client.FilesUpload(c.Dir, files)
+	uploaded, err := uploadFiles(ctx, c.Dir, files, c.Parallel)
 	if err != nil {
 		return err
 	}
 
-	fmt.Printf("Uploaded files:\n")
-	for _, file := range uploaded {
-		fmt.Printf(" * %s\n", file)
-	}
-	return nil
+	return ctx.print(uploaded, func() {
+		fmt.Printf("Uploaded files:\n")
+		for _, file := range uploaded {
+			fmt.Printf(" * %s\n", file)
+		}
+	})
 }
 
 type FileDeleteCmd struct {
@@ -100,13 +105,45 @@ func (c *FileDeleteCmd) Run(ctx *Context) error {
 		if err != nil {
 			return err
 		}
-		fmt.Printf("Delete file '%s/%s'\n", c.Dir, *c.File)
+		ctx.printText(func() {
+			fmt.Printf("Delete file '%s/%s'\n", c.Dir, *c.File)
+		})
 	} else {
 		err = ctx.client.FilesDeleteDir(c.Dir)
 		if err != nil {
 			return err
 		}
-		fmt.Printf("Delete directory '%s'\n", c.Dir)
+		ctx.printText(func() {
+			fmt.Printf("Delete directory '%s'\n", c.Dir)
+		})
 	}
 	return nil
 }
+
+// retries of failed files with --parallel, in addition to --retries of every request
+const (
+	parallelUploadAttempts = 3
+	parallelUploadWait     = time.Second
+)
+
+// uploadFiles upload files to dir, with parallel > 1 every file is sent on its own, failed files are uploaded again and listed
+func uploadFiles(ctx *Context, dir string, files []string, parallel int) ([]string, error) {
+	if parallel <= 1 {
+		return ctx.client.FilesUpload(dir, files)
+	}
+
+	results, err := ctx.client.FilesUploadParallel(dir, files, aptly.ParallelUploadOptions{
+		Parallel: parallel,
+		Attempts: parallelUploadAttempts,
+		WaitTime: parallelUploadWait,
+	})
+	uploaded := []string{}
+	for _, result := range results {
+		if result.Err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to upload %s: %v\n", result.File, result.Err)
+		} else {
+			uploaded = append(uploaded, result.Uploaded...)
+		}
+	}
+	return uploaded, err
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	aptly "raptly/pkg/rest-aptly"
+	"slices"
+	"strings"
+)
+
+type GraphCmd struct {
+	Format string `kong:"name='format',default='text',enum='text,svg,png,dot',help='output format: text is built by raptly, svg and png require graphviz on the server'"`
+	Layout string `kong:"name='layout',default='horizontal',enum='horizontal,vertical',help='graph layout, ignored for text'"`
+	File   string `kong:"name='file',help='write graph to file instead of stdout'"`
+}
+
+// useOutputAsFile support `graph --output FILE` of raptly versions before the output format flag
+func (c *GraphCmd) useOutputAsFile(output *string) {
+	if c.File != "" || slices.Contains(outputFormats, *output) {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "Warning: graph --output FILE is deprecated, use --file %s\n", *output)
+	c.File = *output
+	*output = outputText
+}
+
+func (c *GraphCmd) Run(ctx *Context) error {
+	var out io.Writer = os.Stdout
+	if c.File != "" {
+		f, err := os.Create(c.File)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+
+	if c.Format == "text" {
+		return writeTextGraph(ctx.client, out)
+	}
+
+	graph, err := ctx.client.Graph(c.Format, c.Layout)
+	if err != nil {
+		return err
+	}
+	_, err = out.Write(graph)
+	return err
+}
+
+// writeTextGraph print published repositories as tree of their sources
+func writeTextGraph(client *aptly.Client, out io.Writer) error {
+	lists, err := client.PublishList()
+	if err != nil {
+		return err
+	}
+	snaps, err := client.SnapshotList()
+	if err != nil {
+		return err
+	}
+
+	// list does not contain the sources of the snapshots
+	snapshots := make(map[string]aptly.Snapshot)
+	for _, snap := range snaps {
+		details, err := client.SnapshotShow(snap.Name)
+		if err != nil {
+			return err
+		}
+		snapshots[snap.Name] = details
+	}
+
+	// snapshots used by publishes or other snapshots are printed as part of their tree
+	used := make(map[string]bool)
+	for _, list := range lists {
+		if list.SourceKind == aptly.SourceSnapshot {
+			for _, src := range list.Sources {
+				used[src.Name] = true
+			}
+		}
+	}
+	for _, snap := range snapshots {
+		for _, src := range snap.Snapshots {
+			used[src.Name] = true
+		}
+	}
+
+	for _, list := range lists {
+		fmt.Fprintf(out, "%s %v\n", formatPublishedPath(&list), list.Architectures)
+		for i, src := range list.Sources {
+			last := i == len(list.Sources)-1
+			label := fmt.Sprintf("%s: %s", src.Component, sourceLabel(list.SourceKind, src.Name))
+			printGraphNode(out, "", last, label)
+			if list.SourceKind == aptly.SourceSnapshot {
+				printSnapshotSources(out, snapshots, src.Name, childPrefix("", last))
+			}
+		}
+	}
+
+	for _, snap := range snaps {
+		if used[snap.Name] {
+			continue
+		}
+		fmt.Fprintf(out, "%s\n", sourceLabel(aptly.SourceSnapshot, snap.Name))
+		printSnapshotSources(out, snapshots, snap.Name, "")
+	}
+	return nil
+}
+
+func printSnapshotSources(out io.Writer, snapshots map[string]aptly.Snapshot, name string, prefix string) {
+	snap, ok := snapshots[name]
+	if !ok {
+		return
+	}
+
+	labels := []string{}
+	for _, repo := range snap.LocalRepos {
+		labels = append(labels, sourceLabel(aptly.SourceLocalRepo, repo.Name))
+	}
+	for _, mirror := range snap.RemoteRepos {
+		labels = append(labels, sourceLabel(aptly.SourceRemoteRepo, mirror.Name))
+	}
+	count := len(labels) + len(snap.Snapshots)
+
+	for i, label := range labels {
+		printGraphNode(out, prefix, i == count-1, label)
+	}
+	for i, src := range snap.Snapshots {
+		last := len(labels)+i == count-1
+		printGraphNode(out, prefix, last, sourceLabel(aptly.SourceSnapshot, src.Name))
+		printSnapshotSources(out, snapshots, src.Name, childPrefix(prefix, last))
+	}
+}
+
+func printGraphNode(out io.Writer, prefix string, last bool, label string) {
+	if last {
+		fmt.Fprintf(out, "%s└── %s\n", prefix, label)
+	} else {
+		fmt.Fprintf(out, "%s├── %s\n", prefix, label)
+	}
+}
+
+func childPrefix(prefix string, last bool) string {
+	if last {
+		return prefix + strings.Repeat(" ", 4)
+	}
+	return prefix + "│   "
+}
+
+func sourceLabel(kind string, name string) string {
+	switch kind {
+	case aptly.SourceLocalRepo:
+		return fmt.Sprintf("local repo [%s]", name)
+	case aptly.SourceRemoteRepo:
+		return fmt.Sprintf("mirror [%s]", name)
+	}
+	return fmt.Sprintf("snapshot [%s]", name)
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGraphOutputAsFile(t *testing.T) {
+	cmd := &GraphCmd{}
+	output := "graph.png"
+	cmd.useOutputAsFile(&output)
+	assert.Equal(t, "graph.png", cmd.File)
+	assert.Equal(t, outputText, output)
+
+	// output formats are not files
+	cmd = &GraphCmd{}
+	output = outputJSON
+	cmd.useOutputAsFile(&output)
+	assert.Empty(t, cmd.File)
+	assert.Equal(t, outputJSON, output)
+
+	// --file wins, the output format is rejected later
+	cmd = &GraphCmd{File: "graph.svg"}
+	output = "graph.png"
+	cmd.useOutputAsFile(&output)
+	assert.Equal(t, "graph.svg", cmd.File)
+	assert.Equal(t, "graph.png", output)
+}
@@ -1,10 +1,16 @@
 package main
 
 import (
-	"crypto/tls"
-	"fmt"
+	"cmp"
+	"context"
+	"errors"
+	"maps"
 	"os"
+	"os/signal"
 	aptly "raptly/pkg/rest-aptly"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/alecthomas/kong"
 )
@@ -13,47 +19,233 @@ var Version = "unknown"
 
 type Context struct {
 	client *aptly.Client
+	// config file and the profile given with --profile, empty for the default profile
+	configPath string
+	// the config file was given with --config, it must exist
+	configRequired bool
+	profile        string
+	// output format, text, json or yaml
+	output string
+}
+
+// connectionFlags settings of the connection to the aptly server, unset values are taken from the profile
+type connectionFlags struct {
+	Url          string         `kong:"help='Aptly server API URL, unix:///path/aptly.sock for unix domain sockets',env='RAPTLY_URL'"`
+	Insecure     *bool          `kong:"negatable,help='Allow insecure HTTPS connections'"`
+	CACert       string         `kong:"name='ca-cert',help='PEM file with CA certificates to verify the server instead of the system CAs',env='RAPTLY_CA_CERT'"`
+	ClientCert   string         `kong:"name='client-cert',help='PEM file with client certificate for mutual TLS',env='RAPTLY_CLIENT_CERT'"`
+	ClientKey    string         `kong:"name='client-key',help='PEM file with private key of the client certificate, read from --client-cert if not set',env='RAPTLY_CLIENT_KEY'"`
+	NoProxy      *bool          `kong:"name='no-proxy',help='Do not use http_proxy or https_proxy variables'"`
+	Proxy        string         `kong:"help='HTTP proxy URL, overrides http_proxy and https_proxy variables',env='RAPTLY_PROXY'"`
+	User         *string        `kong:"help='HTTP basic auth username',env='RAPTLY_USER'"`
+	BasicPW      *string        `kong:"name='basic-pass',help='HTTP basic auth password',env='RAPTLY_BASIC_PASS'"`
+	Token        *string        `kong:"help='HTTP bearer token, e.g. for OAuth2 proxies',env='RAPTLY_TOKEN'"`
+	TokenCommand string         `kong:"name='token-command',help='command printing the bearer token, run with sh -c',env='RAPTLY_TOKEN_COMMAND'"`
+	Header       []string       `kong:"sep='none',help='additional HTTP header in the form Name:value, can be repeated'"`
+	Netrc        *bool          `kong:"negatable,help='HTTP basic auth with the credentials of the server in $NETRC or ~/.netrc'"`
+	Retries      *int           `kong:"help='retry failed requests on gateway errors and locked database (default 0)',env='RAPTLY_RETRIES'"`
+	RetryWait    *time.Duration `kong:"name='retry-wait',help='wait time before the first retry, doubled for every further retry (default 1s)',env='RAPTLY_RETRY_WAIT'"`
+}
+
+// applyProfile set all values not given as flag or environment variable from the profile
+func (f *connectionFlags) applyProfile(p *profile) {
+	if f.Url == "" {
+		f.Url = p.Url
+	}
+	if f.Insecure == nil {
+		f.Insecure = p.Insecure
+	}
+	if f.CACert == "" {
+		f.CACert = p.CACert
+	}
+	if f.ClientCert == "" {
+		f.ClientCert = p.ClientCert
+	}
+	if f.ClientKey == "" {
+		f.ClientKey = p.ClientKey
+	}
+	if f.NoProxy == nil {
+		f.NoProxy = p.NoProxy
+	}
+	if f.Proxy == "" {
+		f.Proxy = p.Proxy
+	}
+	// flags for one auth method replace the auth method of the profile
+	if f.User == nil && f.Token == nil && f.TokenCommand == "" && f.Netrc == nil {
+		f.User = p.User
+		f.Token = p.Token
+		f.TokenCommand = p.TokenCommand
+		f.Netrc = p.Netrc
+	}
+	if f.BasicPW == nil {
+		f.BasicPW = p.BasicPW
+	}
+	if len(f.Header) == 0 {
+		for _, name := range slices.Sorted(maps.Keys(p.Headers)) {
+			f.Header = append(f.Header, name+": "+p.Headers[name])
+		}
+	}
+	if f.Retries == nil {
+		f.Retries = p.Retries
+	}
+	if f.RetryWait == nil {
+		f.RetryWait = p.RetryWait
+	}
+}
+
+// newClient create the client for the connection settings
+func (f *connectionFlags) newClient() (*aptly.Client, error) {
+	if f.Url == "" {
+		return nil, errors.New("no aptly server URL, use --url, RAPTLY_URL or a profile of the config file")
+	}
+	if err := validateUrl(f.Url); err != nil {
+		return nil, err
+	}
+
+	client := aptly.NewClient(f.Url)
+	insecure := f.Insecure != nil && *f.Insecure
+	if insecure || f.CACert != "" || f.ClientCert != "" || f.ClientKey != "" {
+		tlsConfig, err := aptly.NewTLSConfig(aptly.TLSOptions{
+			CACert:     f.CACert,
+			ClientCert: f.ClientCert,
+			ClientKey:  f.ClientKey,
+			Insecure:   insecure,
+		})
+		if err != nil {
+			return nil, err
+		}
+		client.SetTLSConfig(tlsConfig)
+	}
+	if f.Retries != nil && *f.Retries > 0 {
+		retryWait := time.Second
+		if f.RetryWait != nil {
+			retryWait = *f.RetryWait
+		}
+		client.SetRetryPolicy(aptly.NewRetryPolicy(*f.Retries+1, retryWait))
+	}
+	if f.NoProxy != nil && *f.NoProxy {
+		client.GetClient().RemoveProxy()
+	} else if f.Proxy != "" {
+		client.GetClient().SetProxy(f.Proxy)
+	}
+	auth, err := f.authenticator()
+	if err != nil {
+		return nil, err
+	}
+	if auth != nil {
+		client.SetAuth(auth)
+	}
+	return client, nil
 }
 
 func main() {
 	var cli struct {
 		Version kong.VersionFlag `name:"version" help:"Print version information and quit"`
 
-		Url      string  `kong:"required,help='Aptly server API URL',env='RAPTLY_URL'"`
-		Insecure bool    `kong:"optional,help='Allow insecure HTTPS connections'"`
-		NoProxy  bool    `kong:"optional,help='Do not use http_proxy or https_proxy variables'"`
-		User     *string `kong:"help='HTTP basic auth username',env='RAPTLY_USER'"`
-		BasicPW  *string `kong:"name='basic-pass',help='HTTP basic auth password',env='RAPTLY_BASIC_PASS'"`
+		ConfigFile string          `kong:"name='config',help='Config file with server profiles (default: ${config})',env='RAPTLY_CONFIG'"`
+		Profile    string          `kong:"help='Profile of the config file to use',env='RAPTLY_PROFILE'"`
+		Connection connectionFlags `kong:"embed"`
+		Output     string          `kong:"default='text',help='Output format: text, json or yaml',env='RAPTLY_OUTPUT'"`
 
 		Repo     RepoCLI     `kong:"cmd,help='Repository management commands',group='Repo'"`
+		Mirror   MirrorCLI   `kong:"cmd,help='Mirror management commands',group='Mirror'"`
 		Publish  publishCLI  `kong:"cmd,help='Published lists commands',group='publish'"`
 		Snapshot SnapshotCLI `kong:"cmd,help='Snapshot lists commands',group='snapshot'"`
 		Package  PkgsCLI     `kong:"cmd,help='Package search commands',group='package'"`
 		Files    FilesCLI    `kong:"cmd,help='Uploaded file management commands',group='Files'"`
 		Status   StatusCLI   `kong:"cmd,help='Aptly server status command',group='Status'"`
+		Task     TaskCLI     `kong:"cmd,help='Background task commands',group='Task'"`
+		Db       DbCLI       `kong:"cmd,help='Database maintenance commands',group='Db'"`
+		Graph    GraphCmd    `kong:"cmd,help='Display graph of dependencies between aptly objects',group='Graph'"`
+		Config   ConfigCLI   `kong:"cmd,help='Config file commands',group='Config'"`
+		Plan     PlanCmd     `kong:"cmd,help='Show changes required to match a YAML spec',group='Spec'"`
+		Apply    ApplyCmd    `kong:"cmd,help='Create and update objects to match a YAML spec',group='Spec'"`
+		Promote  PromoteCmd  `kong:"cmd,help='Snapshot a repository and switch a published snapshot to it',group='Promote'"`
 	}
 
 	ctx := kong.Parse(&cli,
-		kong.Vars{"version": Version})
+		kong.Vars{"version": Version, "config": defaultConfigPath()})
 
-	client := aptly.NewClient(cli.Url)
-	if cli.Insecure {
-		client.GetClient().SetTLSClientConfig(&tls.Config{InsecureSkipVerify: true})
+	// --output is checked here instead of as enum of kong to keep `graph --output FILE` working
+	if ctx.Command() == "graph" {
+		cli.Graph.useOutputAsFile(&cli.Output)
 	}
-	if cli.NoProxy {
-		client.GetClient().RemoveProxy()
+	if !slices.Contains(outputFormats, cli.Output) {
+		ctx.Fatalf("--output must be one of text, json or yaml but got '%s'", cli.Output)
+	}
+
+	runCtx := &Context{
+		configPath:     cmp.Or(cli.ConfigFile, defaultConfigPath()),
+		configRequired: cli.ConfigFile != "",
+		profile:        cli.Profile,
+		output:         cli.Output,
 	}
-	if cli.User != nil {
-		if cli.BasicPW != nil {
-			client.GetClient().SetBasicAuth(*cli.User, *cli.BasicPW)
-		} else {
-			fmt.Fprintf(os.Stderr, "Basic auth username set but no password, define RAPTLY_BASIC_PASS environment variable or use --basic-pass\n")
-			os.Exit(1)
+
+	// config commands work without server and report errors of the config file themselves
+	if !strings.HasPrefix(ctx.Command(), "config ") {
+		config, err := runCtx.loadConfig()
+		ctx.FatalIfErrorf(err)
+		p, err := config.profile(runCtx.profile)
+		ctx.FatalIfErrorf(err)
+		if p != nil {
+			cli.Connection.applyProfile(p)
+		}
+		client, err := cli.Connection.newClient()
+		ctx.FatalIfErrorf(err)
+		if isTerminal(os.Stderr) {
+			client.SetUploadProgress(newUploadProgressBar(os.Stderr).Update)
 		}
+		runCtx.client = client
 	}
 
-	err := ctx.Run(&Context{client: client})
-	ctx.FatalIfErrorf(err)
+	// cancel in-flight requests on Ctrl-C
+	sigCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	if runCtx.client != nil {
+		runCtx.client = runCtx.client.WithContext(sigCtx)
+	}
+	err := ctx.Run(runCtx)
+	stop()
+	ctx.FatalIfErrorf(withExitCode(err))
 
 	os.Exit(0)
 }
+
+// exit codes for errors reported by the server
+const (
+	// plan found differences between spec and server
+	exitDrift        = 2
+	exitNotFound     = 3
+	exitConflict     = 4
+	exitLocked       = 5
+	exitUnauthorized = 6
+)
+
+// exitError sets the exit code of kong's FatalIfErrorf
+type exitError struct {
+	error
+	code int
+}
+
+func (e exitError) Unwrap() error {
+	return e.error
+}
+
+func (e exitError) ExitCode() int {
+	return e.code
+}
+
+func withExitCode(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, aptly.ErrNotFound):
+		return exitError{err, exitNotFound}
+	case errors.Is(err, aptly.ErrConflict):
+		return exitError{err, exitConflict}
+	case errors.Is(err, aptly.ErrLocked):
+		return exitError{err, exitLocked}
+	case errors.Is(err, aptly.ErrUnauthorized):
+		return exitError{err, exitUnauthorized}
+	}
+	return err
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	aptly "raptly/pkg/rest-aptly"
+	"slices"
+)
+
+type MirrorCLI struct {
+	List   mirrorListCmd   `kong:"cmd,help='get list of all mirrors on server'"`
+	Show   mirrorShowCmd   `kong:"cmd,help='display detailed information about mirror, possibly listing all packages'"`
+	Create mirrorCreateCmd `kong:"cmd,help='create mirror of remote repository'"`
+	Edit   mirrorEditCmd   `kong:"cmd,help='change settings of the mirror, the mirror is updated afterwards'"`
+	Rename mirrorRenameCmd `kong:"cmd,help='change name of the mirror, the mirror is updated afterwards'"`
+	Update mirrorUpdateCmd `kong:"cmd,help='download the latest state of the remote repository'"`
+	Drop   mirrorDropCmd   `kong:"cmd,help='deletes information about the mirror'"`
+	Search mirrorSearchCmd `kong:"cmd,help='search mirror for packages matching query'"`
+}
+
+type mirrorListCmd struct{}
+
+func (c *mirrorListCmd) Run(ctx *Context) error {
+	mirrors, err := ctx.client.MirrorList()
+	if err != nil {
+		return err
+	}
+
+	return ctx.print(mirrors, func() {
+		fmt.Println("List of mirrors:")
+		for _, mirror := range mirrors {
+			fmt.Printf(" * [%s]: %s %s\n", mirror.Name, mirror.ArchiveRoot, mirror.Distribution)
+		}
+	})
+}
+
+// mirrorShowOutput mirror with its packages for json and yaml output
+type mirrorShowOutput struct {
+	aptly.RemoteRepo
+	Packages []aptly.Package `json:"Packages,omitempty"`
+}
+
+type mirrorShowCmd struct {
+	Name         string `kong:"arg,help='mirror name'"`
+	WithPackages bool   `kong:"name='with-packages',help='show detailed list of packages and versions stored in the mirror'"`
+}
+
+func (c *mirrorShowCmd) Run(ctx *Context) error {
+	mirror, err := ctx.client.MirrorShow(c.Name)
+	if err != nil {
+		return err
+	}
+
+	packages, err := ctx.client.MirrorPackages(c.Name, aptly.ListPackagesOptions{})
+	if err != nil {
+		return err
+	}
+
+	out := mirrorShowOutput{RemoteRepo: mirror}
+	if c.WithPackages {
+		out.Packages = packages
+	}
+	return ctx.print(out, func() {
+		c.printText(mirror, packages)
+	})
+}
+
+func (c *mirrorShowCmd) printText(mirror aptly.RemoteRepo, packages []aptly.Package) {
+	fmt.Printf("Name: %s\n", mirror.Name)
+	fmt.Printf("Archive Root URL: %s\n", mirror.ArchiveRoot)
+	fmt.Printf("Distribution: %s\n", mirror.Distribution)
+	fmt.Printf("Components: %v\n", mirror.Components)
+	fmt.Printf("Architectures: %v\n", mirror.Architectures)
+	fmt.Printf("Download Sources: %v\n", mirror.DownloadSources)
+	fmt.Printf("Download .udebs: %v\n", mirror.DownloadUdebs)
+	fmt.Printf("Download Installer: %v\n", mirror.DownloadInstaller)
+	if mirror.Filter != "" {
+		fmt.Printf("Filter: %s\n", mirror.Filter)
+		fmt.Printf("Filter With Deps: %v\n", mirror.FilterWithDeps)
+	}
+	fmt.Printf("Last update: %s\n", mirror.LastDownloadDate)
+	fmt.Printf("Number of packages: %v\n", len(packages))
+	if len(mirror.Meta) > 0 {
+		fmt.Print("Information from release file:\n")
+		keys := slices.Sorted(maps.Keys(mirror.Meta))
+		for _, key := range keys {
+			fmt.Printf("%s: %s\n", key, mirror.Meta[key])
+		}
+	}
+
+	if c.WithPackages {
+		fmt.Print("Packages:\n")
+		for _, pkg := range packages {
+			fmt.Printf("  %s\n", pkg.Key)
+		}
+	}
+}
+
+type mirrorCreateCmd struct {
+	Name               string   `kong:"arg,help='name of the mirror'"`
+	ArchiveURL         string   `kong:"arg,name='archive-url',help='url of the remote repository'"`
+	Distribution       string   `kong:"arg,help='distribution name, for flat repositories use the path ending with /'"`
+	Components         []string `kong:"arg,optional,help='components to mirror, all components if none given'"`
+	Architectures      []string `kong:"name='architectures',help='list of architectures to mirror'"`
+	Filter             string   `kong:"name='filter',help='filter packages in mirror'"`
+	FilterWithDeps     bool     `kong:"name='filter-with-deps',help='when filtering, include dependencies of matching packages as well'"`
+	ForceComponents    bool     `kong:"name='force-components',help='(only with component list) skip check that requested components are listed in Release file'"`
+	ForceArchitectures bool     `kong:"name='force-architectures',help='(only with architecture list) skip check that requested architectures are listed in Release file'"`
+	IgnoreSignatures   bool     `kong:"name='ignore-signatures',help='disable verification of Release file signatures'"`
+	Keyring            []string `kong:"name='keyring',help='gpg keyring to use when verifying Release file (could be specified multiple times)'"`
+	WithInstaller      bool     `kong:"name='with-installer',help='download additional not packaged installer files'"`
+	WithSources        bool     `kong:"name='with-sources',help='download source packages in addition to binary packages'"`
+	WithUdebs          bool     `kong:"name='with-udebs',help='download .udeb packages (Debian installer support)'"`
+}
+
+func (c *mirrorCreateCmd) Run(ctx *Context) error {
+	opts := aptly.MirrorCreateOptions{
+		Distribution:          c.Distribution,
+		Components:            c.Components,
+		Architectures:         c.Architectures,
+		Keyrings:              c.Keyring,
+		Filter:                c.Filter,
+		FilterWithDeps:        c.FilterWithDeps,
+		DownloadSources:       c.WithSources,
+		DownloadUdebs:         c.WithUdebs,
+		DownloadInstaller:     c.WithInstaller,
+		SkipComponentCheck:    c.ForceComponents,
+		SkipArchitectureCheck: c.ForceArchitectures,
+		IgnoreSignatures:      c.IgnoreSignatures,
+	}
+
+	mirror, err := ctx.client.MirrorCreate(c.Name, c.ArchiveURL, opts)
+	if err != nil {
+		return err
+	}
+
+	return ctx.print(mirror, func() {
+		fmt.Printf("Mirror [%s]: %s %s successfully added.\n", mirror.Name, mirror.ArchiveRoot, mirror.Distribution)
+	})
+}
+
+type mirrorEditCmd struct {
+	Name           string   `kong:"arg,help='mirror name'"`
+	ArchiveURL     string   `kong:"name='archive-url',help='change archive url'"`
+	Architectures  []string `kong:"name='architectures',help='list of architectures to mirror'"`
+	Filter         *string  `kong:"name='filter',help='filter packages in mirror'"`
+	FilterWithDeps *bool    `kong:"name='filter-with-deps',negatable,help='when filtering, include dependencies of matching packages as well'"`
+	WithSources    *bool    `kong:"name='with-sources',negatable,help='download source packages in addition to binary packages'"`
+	WithUdebs      *bool    `kong:"name='with-udebs',negatable,help='download .udeb packages (Debian installer support)'"`
+}
+
+func (c *mirrorEditCmd) Run(ctx *Context) error {
+	opts := aptly.MirrorEditOptions{
+		ArchiveURL:      c.ArchiveURL,
+		Architectures:   c.Architectures,
+		Filter:          c.Filter,
+		FilterWithDeps:  c.FilterWithDeps,
+		DownloadSources: c.WithSources,
+		DownloadUdebs:   c.WithUdebs,
+	}
+
+	mirror, err := ctx.client.MirrorEdit(c.Name, opts)
+	if err != nil {
+		return err
+	}
+
+	return ctx.print(mirror, func() {
+		fmt.Printf("Mirror [%s] successfully updated.\n", mirror.Name)
+	})
+}
+
+type mirrorRenameCmd struct {
+	OldName string `kong:"arg,help='current mirror name'"`
+	NewName string `kong:"arg,help='new mirror name'"`
+}
+
+func (c *mirrorRenameCmd) Run(ctx *Context) error {
+	// aptly has no rename-only API, the mirror is downloaded again like with mirror edit
+	mirror, err := ctx.client.MirrorRename(c.OldName, c.NewName)
+	if err != nil {
+		return err
+	}
+
+	return ctx.print(mirror, func() {
+		fmt.Printf("Mirror [%s] -> [%s] has been successfully renamed.\n", c.OldName, mirror.Name)
+	})
+}
+
+type mirrorUpdateCmd struct {
+	Name                 string   `kong:"arg,help='mirror name'"`
+	Force                bool     `kong:"name='force',help='force update mirror even if it is locked by another process'"`
+	IgnoreChecksums      bool     `kong:"name='ignore-checksums',help='ignore checksum mismatches while downloading package files and metadata'"`
+	IgnoreSignatures     bool     `kong:"name='ignore-signatures',help='disable verification of Release file signatures'"`
+	SkipExistingPackages bool     `kong:"name='skip-existing-packages',help='do not check file existence for packages listed in the internal database of the mirror'"`
+	Keyring              []string `kong:"name='keyring',help='gpg keyring to use when verifying Release file (could be specified multiple times)'"`
+	MaxTries             int      `kong:"name='max-tries',help='max download tries till process fails with download error'"`
+	DownloadLimit        int64    `kong:"name='download-limit',help='limit download speed (kbytes/sec)'"`
+	Downloader           string   `kong:"name='downloader',help='downloader to use (e.g. grab)'"`
+}
+
+func (c *mirrorUpdateCmd) Run(ctx *Context) error {
+	opts := aptly.MirrorUpdateOptions{
+		ForceUpdate:          c.Force,
+		IgnoreChecksums:      c.IgnoreChecksums,
+		IgnoreSignatures:     c.IgnoreSignatures,
+		SkipExistingPackages: c.SkipExistingPackages,
+		Keyrings:             c.Keyring,
+		MaxTries:             c.MaxTries,
+		DownloadLimit:        c.DownloadLimit,
+		Downloader:           c.Downloader,
+	}
+
+	mirror, err := ctx.client.MirrorUpdate(c.Name, opts)
+	if err != nil {
+		return err
+	}
+
+	return ctx.print(mirror, func() {
+		fmt.Printf("Mirror `%s` has been successfully updated.\n", mirror.Name)
+	})
+}
+
+type mirrorDropCmd struct {
+	Force bool   `kong:"help='force mirror deletion even if used by snapshots'"`
+	Name  string `kong:"arg,help='mirror name'"`
+}
+
+func (c *mirrorDropCmd) Run(ctx *Context) error {
+	err := ctx.client.MirrorDrop(c.Name, c.Force)
+	if err != nil {
+		return err
+	}
+
+	ctx.printText(func() {
+		fmt.Printf("Mirror `%s` has been removed.\n", c.Name)
+	})
+	return nil
+}
+
+type mirrorSearchCmd struct {
+	Name     string `kong:"arg,help='mirror name'"`
+	Query    string `kong:"arg,optional,help='package query'"`
+	WithDeps bool   `kong:"name='with-deps',help='include dependencies into search results'"`
+}
+
+func (c *mirrorSearchCmd) Run(ctx *Context) error {
+	pkgs, err := ctx.client.MirrorPackages(c.Name, aptly.ListPackagesOptions{Query: c.Query, WithDeps: c.WithDeps})
+	if err != nil {
+		return err
+	}
+
+	return ctx.print(pkgs, func() {
+		for _, pkg := range pkgs {
+			fmt.Printf("%s\n", pkg.Key)
+		}
+	})
+}
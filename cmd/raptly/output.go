@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// output formats of the --output flag
+const (
+	outputText = "text"
+	outputJSON = "json"
+	outputYAML = "yaml"
+)
+
+var outputFormats = []string{outputText, outputJSON, outputYAML}
+
+// print write v to stdout as JSON or YAML, for text output call text instead
+//
+// YAML uses the JSON field names, the result of commands without return value is not printed
+func (ctx *Context) print(v any, text func()) error {
+	switch ctx.output {
+	case outputJSON:
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(v)
+	case outputYAML:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		// JSON is valid YAML, decoding to a node keeps the order of the fields
+		var node yaml.Node
+		if err := yaml.Unmarshal(b, &node); err != nil {
+			return err
+		}
+		resetStyle(&node)
+		encoder := yaml.NewEncoder(os.Stdout)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(&node); err != nil {
+			return err
+		}
+		return encoder.Close()
+	}
+	text()
+	return nil
+}
+
+// printText print only for text output, e.g. messages of commands without result
+func (ctx *Context) printText(text func()) {
+	if ctx.output == outputText {
+		text()
+	}
+}
+
+// resetStyle use block style instead of the JSON flow style
+func resetStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		resetStyle(child)
+	}
+}
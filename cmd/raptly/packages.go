@@ -5,7 +5,7 @@ import (
 )
 
 type PkgsCLI struct {
-	Search PkgSearchCmd `kong:"cmd,help='Search whole package database for packages matching query. Requires Aptly Server 1.6.0'"`
+	Search PkgSearchCmd `kong:"cmd,help='Search whole package database for packages matching query. Emulated for Aptly Server older than 1.6.0'"`
 	// Show PkgShowcmd `kong:"cmd,help='Display details about packages from whole package database. Like 'search' with more information'"`
 }
 
@@ -19,10 +19,11 @@ func (c *PkgSearchCmd) Run(ctx *Context) error {
 		return err
 	}
 
-	for _, pkg := range pkgs {
-		fmt.Printf("%s\n", pkg.Key)
-	}
-	return nil
+	return ctx.print(pkgs, func() {
+		for _, pkg := range pkgs {
+			fmt.Printf("%s\n", pkg.Key)
+		}
+	})
 }
 
 // TODO
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	aptly "raptly/pkg/rest-aptly"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	progressBarWidth    = 20
+	progressRedrawDelay = 100 * time.Millisecond
+)
+
+// isTerminal true if f is attached to a terminal
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// uploadProgressBar draws the progress of the current file and of all files in one line
+type uploadProgressBar struct {
+	out io.Writer
+
+	mu       sync.Mutex
+	lastDraw time.Time
+	finished bool
+}
+
+func newUploadProgressBar(out io.Writer) *uploadProgressBar {
+	return &uploadProgressBar{out: out}
+}
+
+func (p *uploadProgressBar) Update(progress aptly.UploadProgress) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if progress.TotalSent == 0 {
+		// new upload or retry
+		p.finished = false
+	}
+	if p.finished {
+		return
+	}
+
+	done := progress.TotalSize > 0 && progress.TotalSent >= progress.TotalSize
+	newFile := progress.FileSent == 0
+	if !done && !newFile && time.Since(p.lastDraw) < progressRedrawDelay {
+		return
+	}
+	p.lastDraw = time.Now()
+
+	fmt.Fprintf(p.out, "\r\x1b[K%s %s  total %s %s/%s",
+		progress.File,
+		formatProgressBar(progress.FileSent, progress.FileSize),
+		formatProgressBar(progress.TotalSent, progress.TotalSize),
+		formatBytes(progress.TotalSent),
+		formatBytes(progress.TotalSize))
+
+	if done {
+		p.finished = true
+		fmt.Fprintln(p.out)
+	}
+}
+
+// formatProgressBar e.g. [#####---------------]  25%, only the sent bytes if the size is unknown
+func formatProgressBar(sent int64, size int64) string {
+	if size <= 0 {
+		return formatBytes(sent)
+	}
+	done := int(min(sent, size) * progressBarWidth / size)
+	return fmt.Sprintf("[%s%s] %3d%%", strings.Repeat("#", done), strings.Repeat("-", progressBarWidth-done), min(sent, size)*100/size)
+}
+
+// formatBytes e.g. 1.5 MiB
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
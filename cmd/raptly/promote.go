@@ -0,0 +1,201 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	aptly "raptly/pkg/rest-aptly"
+	"slices"
+	"strings"
+	"time"
+)
+
+// promoteSnapshotFormat suffix of snapshots created by promote, sortable by time
+const promoteSnapshotFormat = "20060102-150405"
+
+type PromoteCmd struct {
+	FromRepo     string          `kong:"name='from-repo',xor='source',required,help='local repository to snapshot and promote'"`
+	FromSnapshot string          `kong:"name='from-snapshot',xor='source',required,help='promote an existing snapshot, e.g. to revert a promotion'"`
+	ToPublish    string          `kong:"name='to-publish',required,placeholder='PREFIX/DISTRIBUTION',help='published snapshot to switch, ./DISTRIBUTION for the root prefix'"`
+	Component    string          `kong:"help='component to switch; may be omitted if only one component is published'"`
+	Yes          bool            `kong:"short='y',help='switch without confirmation'"`
+	Signing      signingCommands `kong:"embed"` // shared
+}
+
+// promoteOutput result of a promotion
+type promoteOutput struct {
+	Publish   string
+	Component string
+	Snapshot  string
+	// snapshot published before, promote it again to revert
+	Replaced string
+	Diff     []aptly.PackageDiff
+}
+
+// splitPublishTarget split `<prefix>/<distribution>`, the distribution is after the last slash
+func splitPublishTarget(target string) (string, string, error) {
+	i := strings.LastIndex(target, "/")
+	if i <= 0 || i == len(target)-1 {
+		return "", "", fmt.Errorf("invalid publish '%s', expected PREFIX/DISTRIBUTION", target)
+	}
+	return target[:i], target[i+1:], nil
+}
+
+// promoteSource get the component to switch and the snapshot published for it, component may be empty
+// if only one component is published
+func promoteSource(published aptly.PublishedList, component string) (string, string, error) {
+	if published.SourceKind != "snapshot" {
+		return "", "", fmt.Errorf("%s publishes %s repositories, only published snapshots can be promoted", formatPublishedPath(&published), published.SourceKind)
+	}
+	if component == "" {
+		if len(published.Sources) != 1 {
+			return "", "", fmt.Errorf("%s publishes %d components, --component is required", formatPublishedPath(&published), len(published.Sources))
+		}
+		component = published.Sources[0].Component
+	}
+	i := slices.IndexFunc(published.Sources, func(s aptly.SourceEntry) bool { return s.Component == component })
+	if i == -1 {
+		return "", "", fmt.Errorf("%s does not publish component '%s'", formatPublishedPath(&published), component)
+	}
+	return component, published.Sources[i].Name, nil
+}
+
+func (c *PromoteCmd) Run(ctx *Context) error {
+	if !c.Yes && ctx.output != outputText {
+		// the diff is only shown in text output, do not ask to confirm without it
+		return fmt.Errorf("--output %s requires --yes", ctx.output)
+	}
+	prefix, distribution, err := splitPublishTarget(c.ToPublish)
+	if err != nil {
+		return err
+	}
+	signing, err := c.Signing.MakeSigningOptions()
+	if err != nil {
+		return err
+	}
+
+	published, err := ctx.client.PublishShow(distribution, prefix)
+	if err != nil {
+		return err
+	}
+	component, replaced, err := promoteSource(published, c.Component)
+	if err != nil {
+		return err
+	}
+
+	snapshot := c.FromSnapshot
+	created := false
+	if c.FromRepo != "" {
+		snapshot = fmt.Sprintf("%s-%s", c.FromRepo, time.Now().UTC().Format(promoteSnapshotFormat))
+		// the replaced snapshot is recorded on the server to find it again for a revert
+		description := fmt.Sprintf("Promoted from repo %s to %s (%s), replaces snapshot %s", c.FromRepo, c.ToPublish, component, replaced)
+		if _, err := ctx.client.SnapshotFromRepo(snapshot, c.FromRepo, description); err != nil {
+			return err
+		}
+		created = true
+		ctx.printText(func() {
+			fmt.Printf("Snapshot '%s' successfully created.\n", snapshot)
+		})
+	}
+	if snapshot == replaced {
+		return fmt.Errorf("snapshot '%s' is already published to %s", snapshot, c.ToPublish)
+	}
+
+	// the new snapshot is dropped again if it is not promoted
+	promoted := false
+	if created {
+		defer func() {
+			if promoted {
+				return
+			}
+			cleanup, cancel := cleanupClient(ctx)
+			defer cancel()
+			if err := cleanup.SnapshotDrop(snapshot, false); err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to drop snapshot '%s': %v\n", snapshot, err)
+			}
+		}()
+	}
+
+	diffs, err := ctx.client.SnapshotDiff(replaced, snapshot, false)
+	if err != nil {
+		return err
+	}
+	ctx.printText(func() {
+		fmt.Printf("Changes from '%s' (A) to '%s' (B):\n", replaced, snapshot)
+		printSnapshotDiff(diffs)
+	})
+	if len(diffs) == 0 && created {
+		ctx.printText(func() {
+			fmt.Println("Nothing to promote.")
+		})
+		return nil
+	}
+
+	if !c.Yes {
+		ok, err := confirm(fmt.Sprintf("Switch %s component %s from '%s' to '%s'?", c.ToPublish, component, replaced, snapshot))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errors.New("promotion aborted")
+		}
+	}
+
+	snapshots, err := aptly.PublishSources([]string{component}, []string{snapshot})
+	if err != nil {
+		return err
+	}
+	opts := aptly.PublishUpdateOptions{
+		Signing:   signing,
+		Snapshots: snapshots,
+	}
+	if _, err := ctx.client.PublishUpdateOrSwitch(prefix, distribution, opts); err != nil {
+		return err
+	}
+	promoted = true
+	if ctx.output != outputText {
+		// also logged if only the result on stdout is processed
+		fmt.Fprintf(os.Stderr, "Replaced snapshot '%s' of %s (%s)\n", replaced, c.ToPublish, component)
+	}
+
+	result := promoteOutput{
+		Publish:   c.ToPublish,
+		Component: component,
+		Snapshot:  snapshot,
+		Replaced:  replaced,
+		Diff:      diffs,
+	}
+	return ctx.print(result, func() {
+		fmt.Printf("Promoted snapshot '%s' to %s (%s), replaced snapshot '%s'.\n", snapshot, c.ToPublish, component, replaced)
+		fmt.Printf("Revert with 'raptly promote --from-snapshot %s --to-publish %s --component %s'.\n", replaced, c.ToPublish, component)
+	})
+}
+
+// confirm ask question on stderr and read the answer from stdin, replaced by tests
+var confirm = confirmStdin
+
+func confirmStdin(question string) (bool, error) {
+	stat, err := os.Stdin.Stat()
+	if err != nil {
+		return false, err
+	}
+	if stat.Mode()&os.ModeCharDevice == 0 {
+		return false, errors.New("stdin is not a terminal, confirm with --yes")
+	}
+	return readConfirmation(os.Stdin, os.Stderr, question)
+}
+
+// readConfirmation ask question on out, only an answer starting with y confirms
+func readConfirmation(in io.Reader, out io.Writer, question string) (bool, error) {
+	fmt.Fprintf(out, "%s [y/N] ", question)
+	answer, err := bufio.NewReader(in).ReadString('\n')
+	if errors.Is(err, io.EOF) {
+		fmt.Fprintln(out)
+	} else if err != nil {
+		return false, err
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return strings.HasPrefix(answer, "y"), nil
+}
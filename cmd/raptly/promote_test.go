@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	aptly "raptly/pkg/rest-aptly"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitPublishTarget(t *testing.T) {
+	for target, expected := range map[string][2]string{
+		"debian/bookworm":          {"debian", "bookworm"},
+		"./bookworm":               {".", "bookworm"},
+		"s3:bucket:debian/trixie":  {"s3:bucket:debian", "trixie"},
+		"debian/security/bookworm": {"debian/security", "bookworm"},
+	} {
+		prefix, distribution, err := splitPublishTarget(target)
+		assert.NoError(t, err, target)
+		assert.Equal(t, expected, [2]string{prefix, distribution}, target)
+	}
+
+	for _, target := range []string{"bookworm", "/bookworm", "debian/", ""} {
+		_, _, err := splitPublishTarget(target)
+		assert.EqualError(t, err, "invalid publish '"+target+"', expected PREFIX/DISTRIBUTION")
+	}
+}
+
+func TestPromoteSource(t *testing.T) {
+	single := aptly.PublishedList{Path: "debian/bookworm", SourceKind: "snapshot",
+		Sources: []aptly.SourceEntry{{Component: "main", Name: "s1"}}}
+	multiple := aptly.PublishedList{Path: "debian/bookworm", SourceKind: "snapshot",
+		Sources: []aptly.SourceEntry{{Component: "main", Name: "s1"}, {Component: "contrib", Name: "s2"}}}
+
+	component, replaced, err := promoteSource(single, "")
+	assert.NoError(t, err)
+	assert.Equal(t, "main", component)
+	assert.Equal(t, "s1", replaced)
+
+	component, replaced, err = promoteSource(multiple, "contrib")
+	assert.NoError(t, err)
+	assert.Equal(t, "contrib", component)
+	assert.Equal(t, "s2", replaced)
+
+	_, _, err = promoteSource(multiple, "")
+	assert.EqualError(t, err, "debian/bookworm publishes 2 components, --component is required")
+
+	_, _, err = promoteSource(single, "non-free")
+	assert.EqualError(t, err, "debian/bookworm does not publish component 'non-free'")
+
+	local := aptly.PublishedList{Path: "debian/bookworm", SourceKind: "local",
+		Sources: []aptly.SourceEntry{{Component: "main", Name: "testing"}}}
+	_, _, err = promoteSource(local, "")
+	assert.EqualError(t, err, "debian/bookworm publishes local repositories, only published snapshots can be promoted")
+}
+
+func TestPromoteFromSnapshot(t *testing.T) {
+	ctx := &Context{client: clientForTest(t), output: outputJSON}
+	httpmock.RegisterResponder(http.MethodGet, "http://host.local/api/publish/snap/bookworm",
+		newRawJSONResponder(200, `{"Prefix": "snap", "Distribution": "bookworm", "Path": "snap/bookworm", "SourceKind": "snapshot",
+			"Sources": [{"Component": "main", "Name": "s1"}]}`))
+	httpmock.RegisterResponder(http.MethodGet, "http://host.local/api/snapshots/s1/diff/s2",
+		newRawJSONResponder(200, `[]`))
+	httpmock.RegisterResponder(http.MethodPut, "http://host.local/api/publish/snap/bookworm",
+		newRawJSONResponder(200, `{"Prefix": "snap", "Distribution": "bookworm"}`))
+
+	cmd := &PromoteCmd{FromSnapshot: "s2", ToPublish: "snap/bookworm", Signing: signingCommands{Skip: true}}
+	// the diff is not shown in JSON output
+	assert.EqualError(t, cmd.Run(ctx), "--output json requires --yes")
+	assert.Equal(t, 0, httpmock.GetTotalCallCount())
+
+	cmd.Yes = true
+	assert.NoError(t, cmd.Run(ctx))
+	assert.Equal(t, 1, httpmock.GetCallCountInfo()["PUT http://host.local/api/publish/snap/bookworm"])
+}
+
+func TestReadConfirmation(t *testing.T) {
+	for answer, expected := range map[string]bool{
+		"y\n":   true,
+		"Yes\n": true,
+		"y":     true,
+		"n\n":   false,
+		"\n":    false,
+		"":      false,
+	} {
+		var out bytes.Buffer
+		ok, err := readConfirmation(strings.NewReader(answer), &out, "Switch?")
+		assert.NoError(t, err, answer)
+		assert.Equal(t, expected, ok, answer)
+		assert.True(t, strings.HasPrefix(out.String(), "Switch? [y/N] "), answer)
+	}
+}
+
+// promoteFromRepoForTest register a published snapshot s1 and a repo testing with one package more
+//
+// returns the PUT of the publish and the DELETE of the created snapshot
+func promoteFromRepoForTest(t *testing.T, answer bool) (*Context, string, string) {
+	ctx := &Context{client: clientForTest(t), output: outputText}
+	confirm = func(string) (bool, error) { return answer, nil }
+	t.Cleanup(func() { confirm = confirmStdin })
+
+	httpmock.RegisterResponder(http.MethodGet, "http://host.local/api/publish/snap/bookworm",
+		newRawJSONResponder(200, `{"Prefix": "snap", "Distribution": "bookworm", "Path": "snap/bookworm", "SourceKind": "snapshot",
+			"Sources": [{"Component": "main", "Name": "s1"}]}`))
+	httpmock.RegisterResponder(http.MethodPost, "http://host.local/api/repos/testing/snapshots",
+		newRawJSONResponder(201, `{"Name": "testing-new"}`))
+	httpmock.RegisterRegexpResponder(http.MethodGet, regexp.MustCompile(`^http://host\.local/api/snapshots/s1/diff/testing-\d{8}-\d{6}$`),
+		newRawJSONResponder(200, `[{"Left": null, "Right": "Pamd64 hello 1.0 abc"}]`))
+	httpmock.RegisterRegexpResponder(http.MethodDelete, regexp.MustCompile(`^http://host\.local/api/snapshots/testing-\d{8}-\d{6}$`),
+		newRawJSONResponder(200, `{}`))
+	return ctx, "PUT http://host.local/api/publish/snap/bookworm", `DELETE =~^http://host\.local/api/snapshots/testing-\d{8}-\d{6}$`
+}
+
+func TestPromoteFromRepo(t *testing.T) {
+	ctx, put, drop := promoteFromRepoForTest(t, true)
+	httpmock.RegisterResponder(http.MethodPut, "http://host.local/api/publish/snap/bookworm",
+		newRawJSONResponder(200, `{"Prefix": "snap", "Distribution": "bookworm"}`))
+
+	cmd := &PromoteCmd{FromRepo: "testing", ToPublish: "snap/bookworm", Signing: signingCommands{Skip: true}}
+	assert.NoError(t, cmd.Run(ctx))
+	calls := httpmock.GetCallCountInfo()
+	assert.Equal(t, 1, calls["POST http://host.local/api/repos/testing/snapshots"])
+	assert.Equal(t, 1, calls[put])
+	assert.Equal(t, 0, calls[drop])
+}
+
+func TestPromoteFromRepoDeclined(t *testing.T) {
+	ctx, put, drop := promoteFromRepoForTest(t, false)
+
+	cmd := &PromoteCmd{FromRepo: "testing", ToPublish: "snap/bookworm", Signing: signingCommands{Skip: true}}
+	assert.EqualError(t, cmd.Run(ctx), "promotion aborted")
+	calls := httpmock.GetCallCountInfo()
+	assert.Equal(t, 0, calls[put])
+	// the new snapshot is not kept
+	assert.Equal(t, 1, calls[drop])
+}
+
+func TestPromoteFromRepoFailed(t *testing.T) {
+	ctx, put, drop := promoteFromRepoForTest(t, true)
+	httpmock.RegisterResponder(http.MethodPut, "http://host.local/api/publish/snap/bookworm",
+		newRawJSONResponder(500, `{"error": "unable to update: publish failed"}`))
+
+	cmd := &PromoteCmd{FromRepo: "testing", ToPublish: "snap/bookworm", Signing: signingCommands{Skip: true}}
+	assert.ErrorContains(t, cmd.Run(ctx), "publish failed")
+	calls := httpmock.GetCallCountInfo()
+	assert.Equal(t, 1, calls[put])
+	assert.Equal(t, 1, calls[drop])
+}
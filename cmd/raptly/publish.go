@@ -1,6 +1,7 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	aptly "raptly/pkg/rest-aptly"
@@ -12,11 +13,12 @@ type publishCLI struct {
 	Snapshot publishSnapshotCmd `kong:"cmd,help='Publishes snapshot as repository to be consumed by apt.'"`
 	Update   publishUpdateCmd   `kong:"cmd,help='Re-publishes (updates) published local repository.'"`
 	Switch   publishSwitchCmd   `kong:"cmd,help='Switches in-place published repository with new snapshot contents.'"`
-	Show     publishShowCmd     `kong:"cmd,help='Shows detailed information of published repository. Since Aptly 1.6.0'"`
+	Show     publishShowCmd     `kong:"cmd,help='Shows detailed information of published repository.'"`
 	Drop     publishDropCmd     `kong:"cmd,help='Remove files belonging to published repository.'"`
+	Source   publishSourceCLI   `kong:"cmd,help='Stage changes to the components of published repository, requires Aptly Server 1.6.0.'"`
 }
 
-func formatPublishedRepository(list *aptly.PublishedList) string {
+func formatPublishedSources(list *aptly.PublishedList) string {
 	publishes := ""
 	for i, src := range list.Sources {
 		if i > 0 {
@@ -24,11 +26,24 @@ func formatPublishedRepository(list *aptly.PublishedList) string {
 		}
 		publishes += fmt.Sprintf("%s: [%s]", src.Component, src.Name)
 	}
+	return publishes
+}
+
+// formatPublishedPath path of the published repository, qualified with the storage endpoint
+func formatPublishedPath(list *aptly.PublishedList) string {
+	if list.Storage == "" {
+		return list.Path
+	}
+	return list.Storage + ":" + list.Path
+}
+
+func formatPublishedRepository(list *aptly.PublishedList) string {
+	publishes := formatPublishedSources(list)
 
 	if list.SourceKind == "local" {
-		return fmt.Sprintf("%s %v publishes local {%s}", list.Path, list.Architectures, publishes)
+		return fmt.Sprintf("%s %v publishes local {%s}", formatPublishedPath(list), list.Architectures, publishes)
 	}
-	return fmt.Sprintf("%s %v publishes snaphot(s) {%s}", list.Path, list.Architectures, publishes)
+	return fmt.Sprintf("%s %v publishes snaphot(s) {%s}", formatPublishedPath(list), list.Architectures, publishes)
 }
 
 type publishListCmd struct{}
@@ -38,11 +53,12 @@ func (c *publishListCmd) Run(ctx *Context) error {
 	if err != nil {
 		return err
 	}
-	fmt.Print("Published repositories:\n")
-	for _, list := range lists {
-		fmt.Printf(" * %s\n", formatPublishedRepository(&list))
-	}
-	return nil
+	return ctx.print(lists, func() {
+		fmt.Print("Published repositories:\n")
+		for _, list := range lists {
+			fmt.Printf(" * %s\n", formatPublishedRepository(&list))
+		}
+	})
 }
 
 type publishShowCmd struct {
@@ -55,15 +71,34 @@ func (c *publishShowCmd) Run(ctx *Context) error {
 	if err != nil {
 		return err
 	}
+	return ctx.print(list, func() {
+		printPublishedList(&list)
+	})
+}
+
+func printPublishedList(list *aptly.PublishedList) {
+	if list.Storage != "" {
+		fmt.Printf("Storage: %s\n", list.Storage)
+	}
 	fmt.Printf("Prefix: %s\n", list.Prefix)
 	fmt.Printf("Distribution: %s\n", list.Distribution)
 	fmt.Printf("Architectures: %v\n", list.Architectures)
+	if list.Origin != "" {
+		fmt.Printf("Origin: %s\n", list.Origin)
+	}
+	if list.Label != "" {
+		fmt.Printf("Label: %s\n", list.Label)
+	}
+	if list.NotAutomatic != "" {
+		fmt.Printf("NotAutomatic: %s\n", list.NotAutomatic)
+	}
+	if list.ButAutomaticUpgrades != "" {
+		fmt.Printf("ButAutomaticUpgrades: %s\n", list.ButAutomaticUpgrades)
+	}
 	fmt.Print("Sources:\n")
 	for _, src := range list.Sources {
 		fmt.Printf("  %s: %s [%s]\n", src.Component, src.Name, list.SourceKind)
 	}
-
-	return nil
 }
 
 type publishDropCmd struct {
@@ -83,8 +118,9 @@ func (c *publishDropCmd) Run(ctx *Context) error {
 	if err != nil {
 		return err
 	}
-	fmt.Printf("Dropped %s/%s\n", c.Prefix, c.Distribution)
-
+	ctx.printText(func() {
+		fmt.Printf("Dropped %s/%s\n", c.Prefix, c.Distribution)
+	})
 	return nil
 }
 
@@ -120,61 +156,110 @@ func (cmd *signingCommands) MakeSigningOptions() (aptly.PublishSigningOptions, e
 	return opts, nil
 }
 
+// common publishing options
+type publishOptionsCommands struct {
+	Distribution         *string  `kong:"help='distribution name to publish; guessed from local repository default distribution'"`
+	Component            []string `kong:"help='component names to publish (comma-separated), one for every source; it is taken from local repository default, otherwise it defaults to main'"`
+	Architectures        []string `kong:"name='architectures',help='list of architectures to publish (comma-separated)'"`
+	Label                *string  `kong:"help='label to publish'"`
+	Origin               *string  `kong:"help='origin name to publish'"`
+	NotAutomatic         *string  `kong:"name='notautomatic',help='set value for NotAutomatic field'"`
+	ButAutomaticUpgrades *string  `kong:"name='butautomaticupgrades',help='set value for ButAutomaticUpgrades field'"`
+	ForceOverwrite       *bool    `kong:"name='force-overwrite',negatable,help='overwrite files in package pool in case of mismatch'"`
+	SkipContents         *bool    `kong:"name='skip-contents',negatable,help='do not generate Contents indexes'"`
+	SkipCleanup          *bool    `kong:"name='skip-cleanup',negatable,help='do not remove unreferenced files in prefix/component'"`
+	SkipBz2              *bool    `kong:"name='skip-bz2',negatable,help='do not generate bzipped indexes'"`
+	AcquireByHash        *bool    `kong:"name='acquire-by-hash',negatable,help='provide index files by hash'"`
+	MultiDist            *bool    `kong:"name='multi-dist',negatable,help='enable multiple packages with the same filename in different distributions'"`
+}
+
+func (cmd *publishOptionsCommands) MakePublishOptions() aptly.PublishOptions {
+	return aptly.PublishOptions{
+		Architectures:        cmd.Architectures,
+		Distribution:         cmd.Distribution,
+		Label:                cmd.Label,
+		Origin:               cmd.Origin,
+		ForceOverwrite:       cmd.ForceOverwrite,
+		NotAutomatic:         cmd.NotAutomatic,
+		ButAutomaticUpgrades: cmd.ButAutomaticUpgrades,
+		SkipContents:         cmd.SkipContents,
+		SkipCleanup:          cmd.SkipCleanup,
+		SkipBz2:              cmd.SkipBz2,
+		AcquireByHash:        cmd.AcquireByHash,
+		MultiDist:            cmd.MultiDist,
+	}
+}
+
+// splitSourcesPrefix split positional arguments `<source>... <prefix>`
+func splitSourcesPrefix(args []string) ([]string, string, error) {
+	if len(args) < 2 {
+		return nil, "", errors.New("expected at least one source and the prefix")
+	}
+	return args[:len(args)-1], args[len(args)-1], nil
+}
+
 type publishRepoCmd struct {
-	Name         string          `kong:"arg"`
-	Prefix       string          `kong:"arg"`
-	Distribution *string         `kong:"help='distribution name to publish; guessed from local repository default distribution'"`
-	Component    *string         `kong:"help='component name to publish; it is taken from local repository default, otherwise it defaults to main'"`
-	Signing      signingCommands `kong:"embed"` // shared
+	Args    []string               `kong:"arg,name='name> <prefix',help='local repository names followed by the prefix'"`
+	Options publishOptionsCommands `kong:"embed"` // shared
+	Signing signingCommands        `kong:"embed"` // shared
 }
 
 // TODO signing options
 func (c *publishRepoCmd) Run(ctx *Context) error {
-	opts := aptly.PublishOptions{
-		Distribution: c.Distribution,
-		Component:    c.Component,
+	names, prefix, err := splitSourcesPrefix(c.Args)
+	if err != nil {
+		return err
+	}
+	sources, err := aptly.PublishSources(c.Options.Component, names)
+	if err != nil {
+		return err
 	}
+	opts := c.Options.MakePublishOptions()
 
 	signing, err := c.Signing.MakeSigningOptions()
 	if err != nil {
 		return err
 	}
 
-	list, err := ctx.client.PublishRepo(c.Name, c.Prefix, opts, signing)
+	list, err := ctx.client.PublishRepos(sources, prefix, opts, signing)
 	if err != nil {
 		return err
 	}
-	fmt.Printf("Published: %s\n", list.Path)
-
-	return nil
+	return ctx.print(list, func() {
+		fmt.Printf("Published: %s\n", formatPublishedPath(&list))
+	})
 }
 
 type publishSnapshotCmd struct {
-	Name         string          `kong:"arg"`
-	Prefix       string          `kong:"arg"`
-	Distribution *string         `kong:"help='distribution name to publish; guessed from local repository default distribution'"`
-	Component    *string         `kong:"help='component name to publish; it is taken from local repository default, otherwise it defaults to main'"`
-	Signing      signingCommands `kong:"embed"` // shared
+	Args    []string               `kong:"arg,name='name> <prefix',help='snapshot names followed by the prefix'"`
+	Options publishOptionsCommands `kong:"embed"` // shared
+	Signing signingCommands        `kong:"embed"` // shared
 }
 
 // TODO signing options
 func (c *publishSnapshotCmd) Run(ctx *Context) error {
-	signing, err := c.Signing.MakeSigningOptions()
+	names, prefix, err := splitSourcesPrefix(c.Args)
 	if err != nil {
 		return err
 	}
-	opts := aptly.PublishOptions{
-		Distribution: c.Distribution,
-		Component:    c.Component,
+	sources, err := aptly.PublishSources(c.Options.Component, names)
+	if err != nil {
+		return err
 	}
 
-	list, err := ctx.client.PublishSnapshot(c.Name, c.Prefix, opts, signing)
+	signing, err := c.Signing.MakeSigningOptions()
 	if err != nil {
 		return err
 	}
-	fmt.Printf("Published:  %s\n", list.Path)
+	opts := c.Options.MakePublishOptions()
 
-	return nil
+	list, err := ctx.client.PublishSnapshots(sources, prefix, opts, signing)
+	if err != nil {
+		return err
+	}
+	return ctx.print(list, func() {
+		fmt.Printf("Published:  %s\n", formatPublishedPath(&list))
+	})
 }
 
 type publishUpdateCmd struct {
@@ -197,40 +282,52 @@ func (c *publishUpdateCmd) Run(ctx *Context) error {
 	if err != nil {
 		return err
 	}
-	fmt.Printf("Publish for local repo %s %v publishes {%s: [%s]} has been successfully updated.\n", list.Path, list.Architectures, list.Sources[0].Component, list.Sources[0].Name)
-
-	return nil
+	return ctx.print(list, func() {
+		fmt.Printf("Publish for local repo %s %v publishes {%s} has been successfully updated.\n", formatPublishedPath(&list), list.Architectures, formatPublishedSources(&list))
+	})
 }
 
 type publishSwitchCmd struct {
 	Distribution string          `kong:"arg,help='distribution name of published repository'"`
 	Prefix       string          `kong:"arg"`
-	Snapshot     string          `kong:"arg"`
-	Component    string          `kong:""`
+	Snapshots    []string        `kong:"arg,name='snapshot',help='new snapshots, one for every component'"`
+	Component    []string        `kong:"help='component names to switch (comma-separated), one for every snapshot; may be omitted if only one component is published'"`
 	Signing      signingCommands `kong:"embed"` // shared
 }
 
 // TODO signing options
 func (c *publishSwitchCmd) Run(ctx *Context) error {
+	components := c.Component
+	if len(components) == 0 && len(c.Snapshots) == 1 {
+		// like aptly, use the only published component
+		published, err := ctx.client.PublishShow(c.Distribution, c.Prefix)
+		if err != nil {
+			return err
+		}
+		if len(published.Sources) != 1 {
+			return fmt.Errorf("%s/%s publishes %d components, --component is required", c.Prefix, c.Distribution, len(published.Sources))
+		}
+		components = []string{published.Sources[0].Component}
+	}
+	snapshots, err := aptly.PublishSources(components, c.Snapshots)
+	if err != nil {
+		return err
+	}
+
 	signing, err := c.Signing.MakeSigningOptions()
 	if err != nil {
 		return err
 	}
 	opts := aptly.PublishUpdateOptions{
-		Signing: signing,
-		Snapshots: []aptly.SourceEntryRequest{
-			{
-				Name:      c.Snapshot,
-				Component: &c.Component,
-			},
-		},
+		Signing:   signing,
+		Snapshots: snapshots,
 	}
 
 	list, err := ctx.client.PublishUpdateOrSwitch(c.Prefix, c.Distribution, opts)
 	if err != nil {
 		return err
 	}
-	fmt.Printf("Publish for snapshot %s %v publishes {%s: [%s]} has been successfully updated.\n", list.Path, list.Architectures, list.Sources[0].Component, list.Sources[0].Name)
-
-	return nil
+	return ctx.print(list, func() {
+		fmt.Printf("Publish for snapshot %s %v publishes {%s} has been successfully updated.\n", formatPublishedPath(&list), list.Architectures, formatPublishedSources(&list))
+	})
 }
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"fmt"
+	aptly "raptly/pkg/rest-aptly"
+)
+
+type publishSourceCLI struct {
+	List    publishSourceListCmd    `kong:"cmd,help='List staged source changes of published repository.'"`
+	Add     publishSourceAddCmd     `kong:"cmd,help='Stage new components for published repository.'"`
+	Replace publishSourceReplaceCmd `kong:"cmd,help='Stage replacement of all components of published repository.'"`
+	Update  publishSourceUpdateCmd  `kong:"cmd,help='Stage new sources for existing components of published repository.'"`
+	Remove  publishSourceRemoveCmd  `kong:"cmd,help='Stage removal of components from published repository.'"`
+	Drop    publishSourceDropCmd    `kong:"cmd,help='Discard all staged source changes of published repository.'"`
+	Apply   publishSourceApplyCmd   `kong:"cmd,help='Publish the staged source changes.'"`
+}
+
+// sourceEntries pair components and sources, a component is required for every source
+func sourceEntries(components []string, names []string) ([]aptly.SourceEntry, error) {
+	if len(components) != len(names) {
+		return nil, fmt.Errorf("mismatch in number of components (%d) and sources (%d)", len(components), len(names))
+	}
+
+	entries := make([]aptly.SourceEntry, 0, len(names))
+	for i, name := range names {
+		entries = append(entries, aptly.SourceEntry{Component: components[i], Name: name})
+	}
+	return entries, nil
+}
+
+func printApplyHint() {
+	fmt.Println("Apply the changes with 'raptly publish source apply'.")
+}
+
+type publishSourceListCmd struct {
+	Distribution string `kong:"arg"`
+	Prefix       string `kong:"arg"`
+}
+
+func (c *publishSourceListCmd) Run(ctx *Context) error {
+	sources, err := ctx.client.PublishSourcesList(c.Prefix, c.Distribution)
+	if err != nil {
+		return err
+	}
+
+	return ctx.print(sources, func() {
+		fmt.Printf("Sources of %s/%s:\n", c.Prefix, c.Distribution)
+		for _, src := range sources {
+			fmt.Printf("  %s: %s\n", src.Component, src.Name)
+		}
+	})
+}
+
+type publishSourceAddCmd struct {
+	Distribution string   `kong:"arg"`
+	Prefix       string   `kong:"arg"`
+	Sources      []string `kong:"arg,name='source',help='snapshots or local repositories, one for every component'"`
+	Component    []string `kong:"required,help='component names to add (comma-separated), one for every source'"`
+}
+
+func (c *publishSourceAddCmd) Run(ctx *Context) error {
+	entries, err := sourceEntries(c.Component, c.Sources)
+	if err != nil {
+		return err
+	}
+
+	var list aptly.PublishedList
+	for _, entry := range entries {
+		list, err = ctx.client.PublishSourcesAdd(c.Prefix, c.Distribution, entry)
+		if err != nil {
+			return err
+		}
+		ctx.printText(func() {
+			fmt.Printf("Added %s: [%s]\n", entry.Component, entry.Name)
+		})
+	}
+	return ctx.print(list, printApplyHint)
+}
+
+type publishSourceReplaceCmd struct {
+	Distribution string   `kong:"arg"`
+	Prefix       string   `kong:"arg"`
+	Sources      []string `kong:"arg,name='source',help='snapshots or local repositories, one for every component'"`
+	Component    []string `kong:"required,help='component names (comma-separated), one for every source'"`
+}
+
+func (c *publishSourceReplaceCmd) Run(ctx *Context) error {
+	entries, err := sourceEntries(c.Component, c.Sources)
+	if err != nil {
+		return err
+	}
+
+	list, err := ctx.client.PublishSourcesSet(c.Prefix, c.Distribution, entries)
+	if err != nil {
+		return err
+	}
+	return ctx.print(list, func() {
+		for _, entry := range entries {
+			fmt.Printf("Staged %s: [%s]\n", entry.Component, entry.Name)
+		}
+		printApplyHint()
+	})
+}
+
+type publishSourceUpdateCmd struct {
+	Distribution string   `kong:"arg"`
+	Prefix       string   `kong:"arg"`
+	Sources      []string `kong:"arg,name='source',help='snapshots or local repositories, one for every component'"`
+	Component    []string `kong:"required,help='component names to update (comma-separated), one for every source'"`
+}
+
+func (c *publishSourceUpdateCmd) Run(ctx *Context) error {
+	entries, err := sourceEntries(c.Component, c.Sources)
+	if err != nil {
+		return err
+	}
+
+	var list aptly.PublishedList
+	for _, entry := range entries {
+		list, err = ctx.client.PublishSourcesUpdate(c.Prefix, c.Distribution, entry)
+		if err != nil {
+			return err
+		}
+		ctx.printText(func() {
+			fmt.Printf("Updated %s: [%s]\n", entry.Component, entry.Name)
+		})
+	}
+	return ctx.print(list, printApplyHint)
+}
+
+type publishSourceRemoveCmd struct {
+	Distribution string   `kong:"arg"`
+	Prefix       string   `kong:"arg"`
+	Components   []string `kong:"arg,name='component',help='components to remove'"`
+}
+
+func (c *publishSourceRemoveCmd) Run(ctx *Context) error {
+	var list aptly.PublishedList
+	for _, component := range c.Components {
+		var err error
+		list, err = ctx.client.PublishSourcesRemove(c.Prefix, c.Distribution, component)
+		if err != nil {
+			return err
+		}
+		ctx.printText(func() {
+			fmt.Printf("Removed %s\n", component)
+		})
+	}
+	return ctx.print(list, printApplyHint)
+}
+
+type publishSourceDropCmd struct {
+	Distribution string `kong:"arg"`
+	Prefix       string `kong:"arg"`
+}
+
+func (c *publishSourceDropCmd) Run(ctx *Context) error {
+	err := ctx.client.PublishSourcesDrop(c.Prefix, c.Distribution)
+	if err != nil {
+		return err
+	}
+
+	ctx.printText(func() {
+		fmt.Printf("Dropped staged changes of %s/%s\n", c.Prefix, c.Distribution)
+	})
+	return nil
+}
+
+type publishSourceApplyCmd struct {
+	Distribution   string          `kong:"arg"`
+	Prefix         string          `kong:"arg"`
+	ForceOverwrite *bool           `kong:"name='force-overwrite',negatable,help='overwrite files in package pool in case of mismatch'"`
+	SkipContents   *bool           `kong:"name='skip-contents',negatable,help='do not generate Contents indexes'"`
+	SkipCleanup    *bool           `kong:"name='skip-cleanup',negatable,help='do not remove unreferenced files in prefix/component'"`
+	SkipBz2        *bool           `kong:"name='skip-bz2',negatable,help='do not generate bzipped indexes'"`
+	AcquireByHash  *bool           `kong:"name='acquire-by-hash',negatable,help='provide index files by hash'"`
+	MultiDist      *bool           `kong:"name='multi-dist',negatable,help='enable multiple packages with the same filename in different distributions'"`
+	Signing        signingCommands `kong:"embed"` // shared
+}
+
+func (c *publishSourceApplyCmd) Run(ctx *Context) error {
+	signing, err := c.Signing.MakeSigningOptions()
+	if err != nil {
+		return err
+	}
+	opts := aptly.PublishUpdateOptions{
+		ForceOverwrite: c.ForceOverwrite,
+		Signing:        signing,
+		SkipContents:   c.SkipContents,
+		SkipCleanup:    c.SkipCleanup,
+		SkipBz2:        c.SkipBz2,
+		AcquireByHash:  c.AcquireByHash,
+		MultiDist:      c.MultiDist,
+	}
+
+	list, err := ctx.client.PublishSourcesApply(c.Prefix, c.Distribution, opts)
+	if err != nil {
+		return err
+	}
+	return ctx.print(list, func() {
+		fmt.Printf("Publish %s %v publishes {%s} has been successfully updated.\n", formatPublishedPath(&list), list.Architectures, formatPublishedSources(&list))
+	})
+}
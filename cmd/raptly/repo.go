@@ -1,12 +1,15 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"math/rand/v2"
 	"os"
 	"path/filepath"
 	aptly "raptly/pkg/rest-aptly"
+	"strings"
+	"time"
 
 	"pault.ag/go/debian/control"
 )
@@ -32,11 +35,18 @@ func (c *RepoListCmd) Run(ctx *Context) error {
 		return err
 	}
 
-	fmt.Println("List of local repos:")
-	for _, repo := range repos {
-		fmt.Printf(" * [%s]\n", repo.Name)
-	}
-	return nil
+	return ctx.print(repos, func() {
+		fmt.Println("List of local repos:")
+		for _, repo := range repos {
+			fmt.Printf(" * [%s]\n", repo.Name)
+		}
+	})
+}
+
+// repoShowOutput repo with its packages for json and yaml output
+type repoShowOutput struct {
+	aptly.LocalRepo
+	Packages []aptly.Package `json:"Packages,omitempty"`
 }
 
 type RepoShowCmd struct {
@@ -59,19 +69,22 @@ func (c *RepoShowCmd) Run(ctx *Context) error {
 		return err
 	}
 
-	fmt.Printf("Name: %s\n", repo.Name)
-	fmt.Printf("Comment: %s\n", repo.Comment)
-	fmt.Printf("Default Distribution: %s\n", repo.DefaultDistribution)
-	fmt.Printf("Default Component: %s\n", repo.DefaultComponent)
-	fmt.Printf("Number of packages: %v\n", len(packages))
+	out := repoShowOutput{LocalRepo: repo}
 	if c.WithPackages || c.Newest {
-		for _, pkg := range packages {
-			fmt.Printf("  %s\n", pkg.Key)
-		}
+		out.Packages = packages
 	}
-
-	// TODO packages flag
-	return nil
+	return ctx.print(out, func() {
+		fmt.Printf("Name: %s\n", repo.Name)
+		fmt.Printf("Comment: %s\n", repo.Comment)
+		fmt.Printf("Default Distribution: %s\n", repo.DefaultDistribution)
+		fmt.Printf("Default Component: %s\n", repo.DefaultComponent)
+		fmt.Printf("Number of packages: %v\n", len(packages))
+		if c.WithPackages || c.Newest {
+			for _, pkg := range packages {
+				fmt.Printf("  %s\n", pkg.Key)
+			}
+		}
+	})
 }
 
 type RepoCreateCmd struct {
@@ -93,8 +106,9 @@ func (c *RepoCreateCmd) Run(ctx *Context) error {
 		return err
 	}
 
-	fmt.Printf("Repo [%s] successfully added.\n", repo.Name)
-	return nil
+	return ctx.print(repo, func() {
+		fmt.Printf("Repo [%s] successfully added.\n", repo.Name)
+	})
 }
 
 type RepoEditCmd struct {
@@ -116,8 +130,9 @@ func (c *RepoEditCmd) Run(ctx *Context) error {
 		return err
 	}
 
-	fmt.Printf("Repo [%s] successfully updated.\n", repo.Name)
-	return nil
+	return ctx.print(repo, func() {
+		fmt.Printf("Repo [%s] successfully updated.\n", repo.Name)
+	})
 }
 
 type RepoRenameCmd struct {
@@ -135,8 +150,9 @@ func (c *RepoRenameCmd) Run(ctx *Context) error {
 		return err
 	}
 
-	fmt.Printf("Local repository [%s] -> [%s] has been successfully renamed.\n", c.Name, repo.Name)
-	return nil
+	return ctx.print(repo, func() {
+		fmt.Printf("Local repository [%s] -> [%s] has been successfully renamed.\n", c.Name, repo.Name)
+	})
 }
 
 type RepoDropCmd struct {
@@ -149,7 +165,9 @@ func (c *RepoDropCmd) Run(ctx *Context) error {
 	if err != nil {
 		return err
 	}
-	fmt.Printf("Local repo [%s] has been removed.\n", c.Name)
+	ctx.printText(func() {
+		fmt.Printf("Local repo [%s] has been removed.\n", c.Name)
+	})
 	return nil
 }
 
@@ -159,12 +177,13 @@ type RepoRemoveCmd struct {
 }
 
 func (c *RepoRemoveCmd) Run(ctx *Context) error {
-	_, err := ctx.client.ReposRemovePackages(c.Name, c.Packages)
+	repo, err := ctx.client.ReposRemovePackages(c.Name, c.Packages)
 	if err != nil {
 		return err
 	}
-	fmt.Printf("packages dropped.\n")
-	return nil
+	return ctx.print(repo, func() {
+		fmt.Printf("packages dropped.\n")
+	})
 }
 
 const ExtDsc = ".dsc"
@@ -175,12 +194,13 @@ const ExtChanges = ".changes"
 type RepoAddCmd struct {
 	ForceReplace bool `kong:"name='force-replace'"`
 	// RemoveFiles  bool   `kong:"name='remove-files'"`
-	Name string `kong:"arg"`
-	Path string `kong:"arg"`
+	Parallel int    `kong:"default='1',help='upload files in up to N concurrent requests, one per file'"`
+	Atomic   bool   `kong:"help='remove the added packages again if any file fails'"`
+	Name     string `kong:"arg"`
+	Path     string `kong:"arg"`
 }
 
 func (c *RepoAddCmd) Run(ctx *Context) error {
-	dir := fmt.Sprintf("upload_%s", randSeq(8))
 	filesToUpload := []string{}
 
 	fi, err := os.Stat(c.Path)
@@ -228,19 +248,150 @@ func (c *RepoAddCmd) Run(ctx *Context) error {
 		}
 	}
 
-	_, err = ctx.client.FilesUpload(dir, filesToUpload)
+	err = addUploaded(ctx, c.Name, filesToUpload, c.Parallel, c.Atomic, func(dir string) (aptly.RepoAddResult, error) {
+		return ctx.client.ReposAddDirectory(c.Name, dir, aptly.RepoAddOptions{ForceReplace: c.ForceReplace})
+	})
 	if err != nil {
 		return err
 	}
-	res, err := ctx.client.ReposAddDirectory(c.Name, dir, aptly.RepoAddOptions{ForceReplace: c.ForceReplace})
+
+	ctx.printText(func() {
+		fmt.Printf("Added %s\n", c.Path)
+	})
+	return nil
+}
+
+// time allowed for cleanup, also after the command was canceled
+const cleanupTimeout = 30 * time.Second
+
+// cleanupClient client which is not canceled with the command
+func cleanupClient(ctx *Context) (*aptly.Client, context.CancelFunc) {
+	cleanupCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx.client.Context()), cleanupTimeout)
+	return ctx.client.WithContext(cleanupCtx), cancel
+}
+
+// addUploaded upload files to a temporary directory on the server and add them to the repo with add
+//
+// the result of add is printed, in text mode only the report
+// the directory is always deleted, with atomic the changes of the import are rolled back if anything failed
+func addUploaded(ctx *Context, repo string, files []string, parallel int, atomic bool, add func(dir string) (aptly.RepoAddResult, error)) error {
+	dir := fmt.Sprintf("upload_%s", randSeq(8))
+
+	defer func() {
+		cleanup, cancel := cleanupClient(ctx)
+		defer cancel()
+		// the server removes the directory if all files were added
+		err := cleanup.FilesDeleteDir(dir)
+		if err != nil && !errors.Is(err, aptly.ErrNotFound) {
+			fmt.Fprintf(os.Stderr, "Failed to delete upload directory '%s': %v\n", dir, err)
+		}
+	}()
+
+	var before []aptly.Package
+	if atomic {
+		var err error
+		before, err = ctx.client.ReposListPackages(repo, aptly.ListPackagesOptions{})
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err := uploadFiles(ctx, dir, files, parallel)
 	if err != nil {
 		return err
 	}
-	if len(res.FailedFiles) > 0 {
-		return fmt.Errorf("failed files:\n%v", res.FailedFiles)
+
+	res, err := add(dir)
+	if err == nil {
+		// also print the result if some files failed
+		err = ctx.print(res, func() {
+			printAddReport(res.Report)
+		})
+	}
+	if err == nil && len(res.FailedFiles) > 0 {
+		err = fmt.Errorf("failed files:\n%v", res.FailedFiles)
+	}
+	if err != nil && atomic {
+		cleanup, cancel := cleanupClient(ctx)
+		defer cancel()
+		if rollbackErr := rollbackImport(cleanup, repo, before, res.Report); rollbackErr != nil {
+			return errors.Join(err, fmt.Errorf("rollback of repo [%s] failed: %w", repo, rollbackErr))
+		}
+		fmt.Fprintf(os.Stderr, "Changes of the import to repo [%s] have been rolled back\n", repo)
+	}
+	return err
+}
+
+// printAddReport print the changes made by an import, warnings go to stderr
+func printAddReport(report aptly.RepoAddReport) {
+	for _, warning := range report.Warnings {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+	}
+	for _, added := range report.Added {
+		fmt.Printf("  %s\n", added)
+	}
+	for _, removed := range report.Removed {
+		fmt.Printf("  %s\n", removed)
+	}
+}
+
+// packageRef reference of pkg like in the import reports of aptly, e.g. hello_1.0_amd64
+func packageRef(pkg aptly.Package) string {
+	return fmt.Sprintf("%s_%s_%s", pkg.Package, pkg.Version.String(), pkg.Architecture)
+}
+
+// reportedRefs package references of report lines like "hello_1.0_amd64 added"
+func reportedRefs(lines []string) map[string]bool {
+	refs := make(map[string]bool)
+	for _, line := range lines {
+		if ref, _, ok := strings.Cut(line, " "); ok {
+			refs[ref] = true
+		}
+	}
+	return refs
+}
+
+// rollbackImport remove the packages added by an import and add the packages it replaced again
+//
+// before are the packages of the repo before the import, packages not in the report are not touched
+func rollbackImport(client *aptly.Client, repo string, before []aptly.Package, report aptly.RepoAddReport) error {
+	added := reportedRefs(report.Added)
+	removed := reportedRefs(report.Removed)
+
+	existed := make(map[string]bool)
+	for _, pkg := range before {
+		existed[pkg.Key] = true
+	}
+
+	removeKeys := []string{}
+	if len(added) > 0 {
+		after, err := client.ReposListPackages(repo, aptly.ListPackagesOptions{})
+		if err != nil {
+			return err
+		}
+		for _, pkg := range after {
+			if added[packageRef(pkg)] && !existed[pkg.Key] {
+				removeKeys = append(removeKeys, pkg.Key)
+			}
+		}
+	}
+	addKeys := []string{}
+	for _, pkg := range before {
+		if removed[packageRef(pkg)] {
+			addKeys = append(addKeys, pkg.Key)
+		}
 	}
 
-	fmt.Printf("Added %s\n", c.Path)
+	if len(removeKeys) > 0 {
+		if _, err := client.ReposRemovePackages(repo, removeKeys); err != nil {
+			return err
+		}
+	}
+	if len(addKeys) > 0 {
+		if _, err := client.ReposAddPackages(repo, addKeys); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -284,12 +435,13 @@ type RepoIncludeCmd struct {
 	ForceReplace     bool   `kong:"name='force-replace'"`
 	AcceptUnsigned   bool   `kong:"name='accept-unsigned'"`
 	IgnoreSignatures bool   `kong:"name='ignore-signatures'"`
+	Parallel         int    `kong:"default='1',help='upload files in up to N concurrent requests, one per file'"`
+	Atomic           bool   `kong:"help='remove the added packages again if any file fails'"`
 	Name             string `kong:"arg"`
 	Path             string `kong:"arg"`
 }
 
 func (c *RepoIncludeCmd) Run(ctx *Context) error {
-	dir := fmt.Sprintf("upload_%s", randSeq(8))
 	filesToUpload := []string{}
 
 	fi, err := os.Stat(c.Path)
@@ -333,19 +485,20 @@ func (c *RepoIncludeCmd) Run(ctx *Context) error {
 		}
 	}
 
-	_, err = ctx.client.FilesUpload(dir, filesToUpload)
-	if err != nil {
-		return err
-	}
-	res, err := ctx.client.ReposAddDirectory(c.Name, dir, aptly.RepoAddOptions{ForceReplace: c.ForceReplace})
+	err = addUploaded(ctx, c.Name, filesToUpload, c.Parallel, c.Atomic, func(dir string) (aptly.RepoAddResult, error) {
+		return ctx.client.ReposIncludeDirectory(c.Name, dir, aptly.RepoIncludeOptions{
+			ForceReplace:    c.ForceReplace,
+			AcceptUnsigned:  c.AcceptUnsigned,
+			IgnoreSignature: c.IgnoreSignatures,
+		})
+	})
 	if err != nil {
 		return err
 	}
-	if len(res.FailedFiles) > 0 {
-		return fmt.Errorf("failed files:\n%v", res.FailedFiles)
-	}
 
-	fmt.Printf("Added %s\n", c.Path)
+	ctx.printText(func() {
+		fmt.Printf("Included %s\n", c.Path)
+	})
 	return nil
 }
 
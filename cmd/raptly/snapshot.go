@@ -24,11 +24,18 @@ func (c *snapshotListCmd) Run(ctx *Context) error {
 		return err
 	}
 
-	fmt.Println("List of snapshots:")
-	for _, snap := range snaps {
-		fmt.Printf(" * [%s] %s\n", snap.Name, snap.Description)
-	}
-	return nil
+	return ctx.print(snaps, func() {
+		fmt.Println("List of snapshots:")
+		for _, snap := range snaps {
+			fmt.Printf(" * [%s] %s\n", snap.Name, snap.Description)
+		}
+	})
+}
+
+// snapshotShowOutput snapshot with its packages for json and yaml output
+type snapshotShowOutput struct {
+	aptly.Snapshot
+	Packages []aptly.Package `json:"Packages,omitempty"`
 }
 
 type snapshotShowCmd struct {
@@ -48,6 +55,16 @@ func (c *snapshotShowCmd) Run(ctx *Context) error {
 		return err
 	}
 
+	out := snapshotShowOutput{Snapshot: snap}
+	if c.WithPackages || c.Newest {
+		out.Packages = packages
+	}
+	return ctx.print(out, func() {
+		c.printText(snap, packages)
+	})
+}
+
+func (c *snapshotShowCmd) printText(snap aptly.Snapshot, packages []aptly.Package) {
 	fmt.Printf("Name: %s\n", snap.Name)
 	fmt.Printf("CreatedAt: %s\n", snap.CreatedAt)
 	fmt.Printf("Description: %s\n", snap.Description)
@@ -63,11 +80,11 @@ func (c *snapshotShowCmd) Run(ctx *Context) error {
 			fmt.Printf("  %s [%s]\n", ssnap.Name, snap.SourceKind)
 		}
 	}
-	// if snap.RemoteRepos != nil {
-	// 	for _, rrepo := range snap.RemoteRepos {
-	// 		fmt.Printf("  %s [%s]\n", rrepo.Name, snap.SourceKind)
-	// 	}
-	// }
+	if snap.RemoteRepos != nil {
+		for _, rrepo := range snap.RemoteRepos {
+			fmt.Printf("  %s [%s]\n", rrepo.Name, snap.SourceKind)
+		}
+	}
 
 	if c.WithPackages || c.Newest {
 		fmt.Print("Packages:\n")
@@ -75,8 +92,6 @@ func (c *snapshotShowCmd) Run(ctx *Context) error {
 			fmt.Printf("  %s\n", pkg.Key)
 		}
 	}
-
-	return nil
 }
 
 type snapshotDropCmd struct {
@@ -90,7 +105,9 @@ func (c *snapshotDropCmd) Run(ctx *Context) error {
 		return err
 	}
 
-	fmt.Printf("Snapshot `%s` has been dropped.\n", c.Name)
+	ctx.printText(func() {
+		fmt.Printf("Snapshot `%s` has been dropped.\n", c.Name)
+	})
 	return nil
 }
 
@@ -122,8 +139,9 @@ func (c *snapshotCreateCmd) Run(ctx *Context) error {
 	if err != nil {
 		return err
 	}
-	fmt.Printf("Snapshot '%s' successfully created.\n", snap.Name)
-	return nil
+	return ctx.print(snap, func() {
+		fmt.Printf("Snapshot '%s' successfully created.\n", snap.Name)
+	})
 }
 
 type SnapshotDiffCmd struct {
@@ -137,10 +155,17 @@ func (c *SnapshotDiffCmd) Run(ctx *Context) error {
 	if err != nil {
 		return err
 	}
+	return ctx.print(diffs, func() {
+		printSnapshotDiff(diffs)
+	})
+}
+
+// printSnapshotDiff print the differences as table
+func printSnapshotDiff(diffs []aptly.PackageDiff) {
 
 	if len(diffs) == 0 {
 		fmt.Println("Snapshots are identical.")
-		return nil
+		return
 	}
 	const Arch = "Arch"
 	const Pkg = "Package"
@@ -157,12 +182,12 @@ func (c *SnapshotDiffCmd) Run(ctx *Context) error {
 		if pkgDiff.Left != nil {
 			widthArch = max(widthArch, utf8.RuneCountInString(pkgDiff.Left.Architecture))
 			widthPackage = max(widthPackage, utf8.RuneCountInString(pkgDiff.Left.Package))
-			widthA = max(widthA, utf8.RuneCountInString(pkgDiff.Left.Version))
+			widthA = max(widthA, utf8.RuneCountInString(pkgDiff.Left.Version.String()))
 		}
 		if pkgDiff.Right != nil {
 			widthArch = max(widthArch, utf8.RuneCountInString(pkgDiff.Right.Architecture))
 			widthPackage = max(widthPackage, utf8.RuneCountInString(pkgDiff.Right.Package))
-			widthB = max(widthB, utf8.RuneCountInString(pkgDiff.Right.Version))
+			widthB = max(widthB, utf8.RuneCountInString(pkgDiff.Right.Version.String()))
 		}
 	}
 
@@ -203,11 +228,11 @@ func (c *SnapshotDiffCmd) Run(ctx *Context) error {
 
 		a := "-"
 		if pkgDiff.Left != nil {
-			a = pkgDiff.Left.Version
+			a = pkgDiff.Left.Version.String()
 		}
 		b := "-"
 		if pkgDiff.Right != nil {
-			b = pkgDiff.Right.Version
+			b = pkgDiff.Right.Version.String()
 		}
 
 		fmt.Printf("%s %-*s | %-*s | %-*s | %-*s\n",
@@ -218,7 +243,6 @@ func (c *SnapshotDiffCmd) Run(ctx *Context) error {
 			widthB, b,
 		)
 	}
-	return nil
 }
 
 type snapshotRenameCmd struct {
@@ -231,8 +255,9 @@ func (c *snapshotRenameCmd) Run(ctx *Context) error {
 	if err != nil {
 		return err
 	}
-	fmt.Printf("Snapshot %s -> %s has been successfully renamed.\n", c.OldName, snap.Name)
-	return nil
+	return ctx.print(snap, func() {
+		fmt.Printf("Snapshot %s -> %s has been successfully renamed.\n", c.OldName, snap.Name)
+	})
 }
 
 type snapshotMergeCmd struct {
@@ -247,6 +272,7 @@ func (c *snapshotMergeCmd) Run(ctx *Context) error {
 	if err != nil {
 		return err
 	}
-	fmt.Printf("Snapshot %s successfully created.\n", snap.Name)
-	return nil
+	return ctx.print(snap, func() {
+		fmt.Printf("Snapshot %s successfully created.\n", snap.Name)
+	})
 }
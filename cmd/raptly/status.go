@@ -16,8 +16,9 @@ func (c *StatusVersionCmd) Run(ctx *Context) error {
 	if err != nil {
 		return err
 	}
-	fmt.Printf("Aptly Server: %s\n", ver)
-	return nil
+	return ctx.print(ver, func() {
+		fmt.Printf("Aptly Server: %s\n", ver)
+	})
 }
 
 type StatusStorageCmd struct{}
@@ -27,8 +28,9 @@ func (c *StatusStorageCmd) Run(ctx *Context) error {
 	if err != nil {
 		return err
 	}
-	fmt.Printf("Total: %d MiB\n", storage.Total)
-	fmt.Printf("Free: %d MiB\n", storage.Free)
-	fmt.Printf("Percent: %f%%\n", storage.PercentFull)
-	return nil
+	return ctx.print(storage, func() {
+		fmt.Printf("Total: %d MiB\n", storage.Total)
+		fmt.Printf("Free: %d MiB\n", storage.Free)
+		fmt.Printf("Percent: %f%%\n", storage.PercentFull)
+	})
 }
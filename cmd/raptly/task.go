@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+type TaskCLI struct {
+	List   taskListCmd   `kong:"cmd,help='get list of all tasks on the server'"`
+	Show   taskShowCmd   `kong:"cmd,help='display state of the task'"`
+	Wait   taskWaitCmd   `kong:"cmd,help='wait until the task is finished'"`
+	Output taskOutputCmd `kong:"cmd,help='display the log output of the task'"`
+	Clear  taskClearCmd  `kong:"cmd,help='remove all finished tasks from the task list'"`
+}
+
+type taskListCmd struct{}
+
+func (c *taskListCmd) Run(ctx *Context) error {
+	tasks, err := ctx.client.TasksList()
+	if err != nil {
+		return err
+	}
+
+	return ctx.print(tasks, func() {
+		fmt.Println("List of tasks:")
+		for _, task := range tasks {
+			fmt.Printf(" * [%d] %s: %s\n", task.ID, task.Name, task.State)
+		}
+	})
+}
+
+type taskShowCmd struct {
+	ID int `kong:"arg,help='task id'"`
+}
+
+func (c *taskShowCmd) Run(ctx *Context) error {
+	task, err := ctx.client.TasksShow(c.ID)
+	if err != nil {
+		return err
+	}
+
+	return ctx.print(task, func() {
+		fmt.Printf("ID: %d\n", task.ID)
+		fmt.Printf("Name: %s\n", task.Name)
+		fmt.Printf("State: %s\n", task.State)
+	})
+}
+
+type taskWaitCmd struct {
+	ID      int           `kong:"arg,help='task id'"`
+	Timeout time.Duration `kong:"name='timeout',help='give up waiting after this duration, e.g. 30m; waits forever if not set'"`
+}
+
+func (c *taskWaitCmd) Run(ctx *Context) error {
+	task, err := ctx.client.TasksWait(c.ID, c.Timeout)
+	if err != nil {
+		return err
+	}
+
+	return ctx.print(task, func() {
+		fmt.Printf("Task [%d] %s: %s\n", task.ID, task.Name, task.State)
+	})
+}
+
+type taskOutputCmd struct {
+	ID int `kong:"arg,help='task id'"`
+}
+
+func (c *taskOutputCmd) Run(ctx *Context) error {
+	output, err := ctx.client.TasksOutput(c.ID)
+	if err != nil {
+		return err
+	}
+
+	return ctx.print(output, func() {
+		fmt.Print(output)
+	})
+}
+
+type taskClearCmd struct{}
+
+func (c *taskClearCmd) Run(ctx *Context) error {
+	err := ctx.client.TasksClear()
+	if err != nil {
+		return err
+	}
+
+	ctx.printText(func() {
+		fmt.Println("Finished tasks have been cleared.")
+	})
+	return nil
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"net/http"
+	aptly "raptly/pkg/rest-aptly"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+)
+
+func newRawJSONResponder(status int, body string) httpmock.Responder {
+	return func(*http.Request) (*http.Response, error) {
+		resp := httpmock.NewStringResponse(status, body)
+		resp.Header.Set("Content-Type", "application/json")
+		return resp, nil
+	}
+}
+
+// clientForTest client of an aptly 1.6.0 server at http://host.local with httpmock enabled
+func clientForTest(t *testing.T) *aptly.Client {
+	client := aptly.NewClient("http://host.local")
+	httpmock.ActivateNonDefault(client.GetClient().GetClient())
+	t.Cleanup(httpmock.DeactivateAndReset)
+
+	httpmock.RegisterResponder(http.MethodGet, "http://host.local/api/version",
+		newRawJSONResponder(200, `{"Version": "1.6.0"}`))
+	return client
+}
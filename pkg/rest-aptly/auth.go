@@ -0,0 +1,197 @@
+package aptly
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// Authenticator adds credentials to every request sent by the client, also to retries
+type Authenticator interface {
+	Authenticate(req *http.Request) error
+}
+
+// AuthFunc adapts a function to Authenticator
+type AuthFunc func(req *http.Request) error
+
+func (f AuthFunc) Authenticate(req *http.Request) error {
+	return f(req)
+}
+
+// SetAuth authenticate all requests with auth, also of the copies of WithContext, nil to disable
+func (c *Client) SetAuth(auth Authenticator) {
+	c.auth.auth = auth
+}
+
+// clientAuth authenticator of a client, the pre-request hook of resty is only set once by NewClient
+type clientAuth struct {
+	auth Authenticator
+}
+
+func (a *clientAuth) preRequestHook(_ *resty.Client, req *http.Request) error {
+	if a.auth == nil {
+		return nil
+	}
+	return a.auth.Authenticate(req)
+}
+
+// BasicAuth authenticate with HTTP basic auth
+func BasicAuth(user string, password string) Authenticator {
+	return AuthFunc(func(req *http.Request) error {
+		req.SetBasicAuth(user, password)
+		return nil
+	})
+}
+
+// BearerAuth authenticate with a bearer token, e.g. for OAuth2 proxies
+func BearerAuth(token string) Authenticator {
+	return AuthFunc(func(req *http.Request) error {
+		req.Header.Set("Authorization", "Bearer "+token)
+		return nil
+	})
+}
+
+// HeaderAuth set arbitrary headers, e.g. API keys of a proxy
+func HeaderAuth(headers http.Header) Authenticator {
+	return AuthFunc(func(req *http.Request) error {
+		for name, values := range headers {
+			req.Header.Del(name)
+			for _, value := range values {
+				req.Header.Add(name, value)
+			}
+		}
+		return nil
+	})
+}
+
+// MultiAuth apply all authenticators in order, e.g. headers and a bearer token
+func MultiAuth(auths ...Authenticator) Authenticator {
+	return AuthFunc(func(req *http.Request) error {
+		for _, auth := range auths {
+			if err := auth.Authenticate(req); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+type netrcEntry struct {
+	login    string
+	password string
+}
+
+// NetrcAuth authenticate with HTTP basic auth using the credentials of the host in a netrc file
+//
+// The file is read once, hosts without machine entry use the default entry or no authentication
+func NetrcAuth(path string) (Authenticator, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	machines, fallback, err := parseNetrc(f)
+	if err != nil {
+		return nil, fmt.Errorf("invalid netrc file '%s': %w", path, err)
+	}
+
+	return AuthFunc(func(req *http.Request) error {
+		entry, ok := machines[req.URL.Hostname()]
+		if !ok {
+			entry = fallback
+		}
+		if entry != nil {
+			req.SetBasicAuth(entry.login, entry.password)
+		}
+		return nil
+	}), nil
+}
+
+// DefaultNetrcPath path of the netrc file from the NETRC variable or ~/.netrc
+func DefaultNetrcPath() string {
+	if path := os.Getenv("NETRC"); path != "" {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".netrc")
+}
+
+// parseNetrc get the entries by machine name and the default entry, macros are skipped
+func parseNetrc(r io.Reader) (map[string]*netrcEntry, *netrcEntry, error) {
+	machines := make(map[string]*netrcEntry)
+	var fallback *netrcEntry
+	var current *netrcEntry
+
+	scanner := bufio.NewScanner(r)
+	inMacro := false
+	for scanner.Scan() {
+		line := scanner.Text()
+		if inMacro {
+			// macro definitions end with an empty line
+			inMacro = strings.TrimSpace(line) != ""
+			continue
+		}
+
+		tokens := strings.Fields(line)
+		for i := 0; i < len(tokens); i++ {
+			// value of the keyword at i
+			next := func() (string, error) {
+				if i+1 >= len(tokens) {
+					return "", fmt.Errorf("missing value for '%s'", tokens[i])
+				}
+				i++
+				return tokens[i], nil
+			}
+
+			switch tokens[i] {
+			case "machine":
+				name, err := next()
+				if err != nil {
+					return nil, nil, err
+				}
+				current = &netrcEntry{}
+				machines[name] = current
+			case "default":
+				current = &netrcEntry{}
+				fallback = current
+			case "login", "password", "account":
+				keyword := tokens[i]
+				value, err := next()
+				if err != nil {
+					return nil, nil, err
+				}
+				if current == nil {
+					return nil, nil, fmt.Errorf("'%s' outside of machine entry", keyword)
+				}
+				if keyword == "login" {
+					current.login = value
+				} else if keyword == "password" {
+					current.password = value
+				}
+			case "macdef":
+				inMacro = true
+				i = len(tokens)
+			default:
+				if strings.HasPrefix(tokens[i], "#") {
+					i = len(tokens)
+					continue
+				}
+				return nil, nil, fmt.Errorf("unknown token '%s'", tokens[i])
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+	return machines, fallback, nil
+}
@@ -0,0 +1,116 @@
+package aptly
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+)
+
+// register responder for url which returns the Authorization and X-Api-Key headers as version
+func registerAuthEcho(url string) {
+	httpmock.RegisterResponder(http.MethodGet, url+"/api/version",
+		func(req *http.Request) (*http.Response, error) {
+			return httpmock.NewJsonResponse(200, ServerVersion{
+				Version: strings.TrimSpace(req.Header.Get("Authorization") + " " + req.Header.Get("X-Api-Key")),
+			})
+		})
+}
+
+func TestAuth(t *testing.T) {
+	client := clientForTest(t, "http://host.local")
+	registerAuthEcho("http://host.local")
+
+	tests := []struct {
+		name     string
+		auth     Authenticator
+		expected string
+	}{
+		{"none", nil, ""},
+		{"basic", BasicAuth("user", "secret"), "Basic dXNlcjpzZWNyZXQ="},
+		{"bearer", BearerAuth("token"), "Bearer token"},
+		{"header", HeaderAuth(http.Header{"X-Api-Key": {"key"}}), "key"},
+		{"multiple", MultiAuth(BearerAuth("token"), HeaderAuth(http.Header{"X-Api-Key": {"key"}})), "Bearer token key"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client.SetAuth(tt.auth)
+			version, err := client.Version()
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, version.Version)
+		})
+	}
+}
+
+// warningLogger resty logger which records warnings
+type warningLogger struct {
+	warnings []string
+}
+
+func (l *warningLogger) Errorf(string, ...any) {}
+func (l *warningLogger) Debugf(string, ...any) {}
+func (l *warningLogger) Warnf(format string, v ...any) {
+	l.warnings = append(l.warnings, fmt.Sprintf(format, v...))
+}
+
+func TestSetAuthKeepsHook(t *testing.T) {
+	client := clientForTest(t, "http://host.local")
+	registerAuthEcho("http://host.local")
+	logger := &warningLogger{}
+	client.GetClient().SetLogger(logger)
+
+	copied := client.WithContext(t.Context())
+	client.SetAuth(BearerAuth("first"))
+	client.SetAuth(nil)
+	client.SetAuth(BearerAuth("token"))
+	assert.Empty(t, logger.warnings)
+
+	// the copy uses the authenticator set afterwards
+	version, err := copied.Version()
+	assert.NoError(t, err)
+	assert.Equal(t, "Bearer token", version.Version)
+}
+
+func TestNetrcAuth(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "netrc")
+	err := os.WriteFile(path, []byte(`
+# aptly servers
+machine host.local login user password secret
+macdef init
+cd /srv
+machine ignored login macro password macro
+
+machine other.local
+	login other
+	password pw
+default login anonymous password guest
+`), 0o600)
+	assert.NoError(t, err)
+
+	auth, err := NetrcAuth(path)
+	assert.NoError(t, err)
+
+	client := clientForTest(t, "http://host.local:8080")
+	registerAuthEcho("http://host.local:8080")
+	registerAuthEcho("http://unknown.local")
+	client.SetAuth(auth)
+
+	version, err := client.Version()
+	assert.NoError(t, err)
+	assert.Equal(t, "Basic dXNlcjpzZWNyZXQ=", version.Version)
+
+	client.GetClient().SetBaseURL("http://unknown.local")
+	version, err = client.Version()
+	assert.NoError(t, err)
+	assert.Equal(t, "Basic YW5vbnltb3VzOmd1ZXN0", version.Version)
+
+	err = os.WriteFile(path, []byte("login user"), 0o600)
+	assert.NoError(t, err)
+	_, err = NetrcAuth(path)
+	assert.EqualError(t, err, "invalid netrc file '"+path+"': 'login' outside of machine entry")
+}
@@ -0,0 +1,106 @@
+package aptly
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"pault.ag/go/debian/version"
+)
+
+// first version with the new API endpoints
+const aptly160 = "1.6.0"
+
+// Capabilities lists which optional API features the server supports
+type Capabilities struct {
+	// version reported by the server
+	Version string
+	// StorageUsage
+	StorageUsage bool
+	// PackagesSearch
+	PackagesSearch bool
+	// SnapshotMerge
+	SnapshotMerge bool
+	// PublishShow
+	PublishShow bool
+}
+
+func newCapabilities(serverVersion string) Capabilities {
+	return Capabilities{
+		Version:        serverVersion,
+		StorageUsage:   versionAtLeast(serverVersion, aptly160),
+		PackagesSearch: versionAtLeast(serverVersion, aptly160),
+		SnapshotMerge:  versionAtLeast(serverVersion, aptly160),
+		PublishShow:    versionAtLeast(serverVersion, aptly160),
+	}
+}
+
+// versionAtLeast compare debian style versions, unknown formats are assumed to be new enough
+func versionAtLeast(serverVersion string, required string) bool {
+	server, err := version.Parse(serverVersion)
+	if err != nil {
+		return true
+	}
+	req, err := version.Parse(required)
+	if err != nil {
+		return true
+	}
+	return version.Compare(server, req) >= 0
+}
+
+// ErrUnsupportedByServer is matched by UnsupportedByServerError with errors.Is
+var ErrUnsupportedByServer = errors.New("unsupported by server")
+
+// UnsupportedByServerError is returned by methods the server is too old for
+type UnsupportedByServerError struct {
+	// name of the method
+	Feature string
+	// minimum aptly version required
+	RequiredVersion string
+	// version of the server
+	ServerVersion string
+}
+
+func (e *UnsupportedByServerError) Error() string {
+	return fmt.Sprintf("%s requires aptly %s, server version is %s", e.Feature, e.RequiredVersion, e.ServerVersion)
+}
+
+func (e *UnsupportedByServerError) Is(target error) bool {
+	return target == ErrUnsupportedByServer
+}
+
+// cache shared between copies of the client
+type capabilitiesCache struct {
+	mu   sync.Mutex
+	caps *Capabilities
+}
+
+// Capabilities get the features supported by the server, the version is requested once and cached
+func (c *Client) Capabilities() (Capabilities, error) {
+	c.capabilities.mu.Lock()
+	defer c.capabilities.mu.Unlock()
+
+	if c.capabilities.caps != nil {
+		return *c.capabilities.caps, nil
+	}
+
+	ver, err := c.Version()
+	if err != nil {
+		return Capabilities{}, err
+	}
+	caps := newCapabilities(ver.Version)
+	c.capabilities.caps = &caps
+	return caps, nil
+}
+
+// requireVersion fail with UnsupportedByServerError if the server is older than required
+func (c *Client) requireVersion(feature string, required string) error {
+	caps, err := c.Capabilities()
+	if err != nil {
+		return err
+	}
+	if !versionAtLeast(caps.Version, required) {
+		return &UnsupportedByServerError{Feature: feature, RequiredVersion: required, ServerVersion: caps.Version}
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package aptly
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVersionAtLeast(t *testing.T) {
+	assert.True(t, versionAtLeast("1.6.0", "1.6.0"))
+	assert.True(t, versionAtLeast("1.6.1+ds1-2", "1.6.0"))
+	assert.True(t, versionAtLeast("1.10.0", "1.6.0"))
+	assert.False(t, versionAtLeast("1.5.0", "1.6.0"))
+	assert.False(t, versionAtLeast("1.6.0~rc1", "1.6.0"))
+	// unknown versions are passed to the server
+	assert.True(t, versionAtLeast("", "1.6.0"))
+}
+
+func TestCapabilities(t *testing.T) {
+	client := clientForTest(t, "http://host.local")
+
+	httpmock.RegisterResponder(http.MethodGet, "http://host.local/api/version", newRawJSONResponder(200, `{"Version": "1.5.0"}`))
+
+	caps, err := client.Capabilities()
+	assert.NoError(t, err)
+	assert.Equal(t, Capabilities{Version: "1.5.0"}, caps)
+
+	// cached
+	_, err = client.Capabilities()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, httpmock.GetTotalCallCount())
+
+	caps = newCapabilities("1.6.0")
+	assert.Equal(t, Capabilities{
+		Version:        "1.6.0",
+		StorageUsage:   true,
+		PackagesSearch: true,
+		SnapshotMerge:  true,
+		PublishShow:    true,
+	}, caps)
+}
+
+func TestUnsupportedByServer(t *testing.T) {
+	client := clientForTest(t, "http://host.local")
+
+	httpmock.RegisterResponder(http.MethodGet, "http://host.local/api/version", newRawJSONResponder(200, `{"Version": "1.5.0"}`))
+
+	_, err := client.StorageUsage()
+	assert.ErrorIs(t, err, ErrUnsupportedByServer)
+	assert.EqualError(t, err, "StorageUsage requires aptly 1.6.0, server version is 1.5.0")
+
+	client.SetFallbacks(false)
+
+	_, err = client.PackagesSearch("", false)
+	assert.ErrorIs(t, err, ErrUnsupportedByServer)
+
+	_, err = client.PublishShow("bookworm", "prefix")
+	assert.ErrorIs(t, err, ErrUnsupportedByServer)
+
+	_, err = client.SnapshotMerge("dest", []string{"src"}, SnapshotMergeOptions{})
+	var unsupported *UnsupportedByServerError
+	if assert.ErrorAs(t, err, &unsupported) {
+		assert.Equal(t, "SnapshotMerge", unsupported.Feature)
+		assert.Equal(t, "1.6.0", unsupported.RequiredVersion)
+	}
+
+	// only the version was requested
+	assert.Equal(t, 1, httpmock.GetTotalCallCount())
+}
@@ -2,24 +2,86 @@
 package aptly
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
 
 	"github.com/go-resty/resty/v2"
 )
 
 type Client struct {
 	client *resty.Client
+	// context used for all requests, nil for none
+	ctx context.Context
+
+	taskPollInterval time.Duration
+	// shared with the copies of WithContext like the resty client
+	retry          *RetryPolicy
+	auth           *clientAuth
+	capabilities   *capabilitiesCache
+	noFallbacks    bool
+	uploadProgress func(UploadProgress)
 }
 
+// unixScheme prefix of URLs of unix domain sockets, e.g. unix:///run/aptly.sock
+const unixScheme = "unix://"
+
+// NewClient create client for the aptly API at url, e.g. http://localhost:8080
+// or the unix domain socket of `aptly api serve -listen unix:///run/aptly.sock`
 func NewClient(url string) *Client {
 	client := new(Client)
 	client.client = resty.New()
+	if socket, ok := strings.CutPrefix(url, unixScheme); ok {
+		client.client.SetTransport(unixTransport(socket))
+		// the host is ignored by the transport
+		url = "http://unix"
+	}
 	client.client.SetBaseURL(url)
 	client.client.SetError(APIError{})
+	client.client.OnBeforeRequest(uploadMiddleware)
+	client.auth = &clientAuth{}
+	client.client.SetPreRequestHook(client.auth.preRequestHook)
+	client.taskPollInterval = defaultTaskPollInterval
+	client.retry = &RetryPolicy{}
+	client.capabilities = &capabilitiesCache{}
 
 	return client
 }
 
+// unixTransport transport connecting to the unix domain socket instead of the host of the request
+func unixTransport(socket string) *http.Transport {
+	return &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, "unix", socket)
+		},
+		MaxIdleConns:    10,
+		IdleConnTimeout: 90 * time.Second,
+	}
+}
+
+// WithContext get a copy of the client which uses ctx for all requests,
+// e.g. client.WithContext(ctx).ReposList() is canceled with ctx
+//
+// The copy shares the underlying resty client and the retry policy
+func (c *Client) WithContext(ctx context.Context) *Client {
+	client := *c
+	client.ctx = ctx
+	return &client
+}
+
+// Context get the context used for requests, context.Background() if none was set
+func (c *Client) Context() context.Context {
+	if c.ctx == nil {
+		return context.Background()
+	}
+	return c.ctx
+}
+
 // GetClient get resty client used for advanced use cases like testing or special auth
 func (c *Client) GetClient() *resty.Client {
 	return c.client
@@ -47,46 +109,107 @@ func (c *Client) newRequest(method string, url string) *resty.Request {
 	r.ExpectContentType("application/json")
 	r.Method = method
 	r.URL = url
+	if c.ctx != nil {
+		r.SetContext(c.ctx)
+	}
+	r.AddRetryCondition(func(resp *resty.Response, err error) bool {
+		return c.retry.shouldRetry(r, resp, err)
+	})
 	return r
 }
 
 // send prepared request and get error
 func (c *Client) send(req *resty.Request) error {
+	_, err := c.sendRaw(req)
+	return err
+}
+
+// sendRaw like send, but also return the response for results which are not JSON
+func (c *Client) sendRaw(req *resty.Request) (*resty.Response, error) {
 	res, err := req.Send()
 
 	if err != nil {
-		return err
+		return nil, err
 	} else if res.IsSuccess() {
-		return nil
+		return res, nil
 	}
-	return getError(res)
+	return nil, getError(res)
 }
 
+// sentinel errors to check APIError with errors.Is
+var (
+	// object does not exist
+	ErrNotFound = errors.New("not found")
+	// object already exists or is still in use
+	ErrConflict = errors.New("conflict")
+	// database or resource locked by another operation
+	ErrLocked = errors.New("locked")
+	// authentication missing or rejected
+	ErrUnauthorized = errors.New("unauthorized")
+)
+
 type APIError struct {
 	// as pointer to distinguish between valid error and failed parsing errors (like empty bodies)
 	ErrorMsg *string `json:"error,omitempty"`
+
+	// HTTP status code of the response
+	StatusCode int `json:"-"`
+	// HTTP method of the request
+	Method string `json:"-"`
+	// path of the request
+	Path string `json:"-"`
 }
 
 func (e *APIError) Error() string {
 	if e.Valid() {
 		return *e.ErrorMsg
 	}
-	return ""
+	return fmt.Sprintf("%s %s: unexpected response code %v", e.Method, e.Path, e.StatusCode)
 }
 
 func (e *APIError) Valid() bool {
 	return e.ErrorMsg != nil
 }
 
+// Is match the sentinel errors, status codes are checked first,
+// the error message is used for errors aptly reports with generic status codes
+func (e *APIError) Is(target error) bool {
+	msg := ""
+	if e.Valid() {
+		msg = strings.ToLower(*e.ErrorMsg)
+	}
+
+	switch target {
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrConflict:
+		return e.StatusCode == http.StatusConflict ||
+			strings.Contains(msg, "already exists") ||
+			strings.Contains(msg, "already used")
+	case ErrLocked:
+		// messages of aptly when the database is opened by another process
+		return e.StatusCode == http.StatusLocked ||
+			strings.Contains(msg, "database is locked") ||
+			strings.Contains(msg, "resource temporarily unavailable")
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden
+	}
+	return false
+}
+
 // common function to get errors
 func getError(response *resty.Response) error {
-	e := response.Error()
-	if e != nil {
-		apiErr, ok := e.(*APIError)
-		if ok && apiErr.Valid() {
-			return apiErr
-		}
+	apiErr, ok := response.Error().(*APIError)
+	if !ok || apiErr == nil {
+		apiErr = &APIError{}
+	}
+
+	apiErr.StatusCode = response.StatusCode()
+	apiErr.Method = response.Request.Method
+	apiErr.Path = response.Request.URL
+	if response.Request.RawRequest != nil {
+		apiErr.Path = response.Request.RawRequest.URL.Path
 	}
 
-	return fmt.Errorf("unexpected response code %v", response.StatusCode())
+	return apiErr
 }
@@ -1,7 +1,10 @@
 package aptly
 
 import (
+	"context"
+	"net"
 	"net/http"
+	"path/filepath"
 	"testing"
 
 	"github.com/jarcoal/httpmock"
@@ -63,3 +66,92 @@ func TestGetError(t *testing.T) {
 	assert.NoError(t, err)
 	assert.ErrorContains(t, getError(resStatus), "unexpected response code 502")
 }
+
+func TestWithContext(t *testing.T) {
+	client := clientForTest(t, "http://host.local")
+
+	// httpmock does not check the context itself
+	httpmock.RegisterResponder(http.MethodGet, "http://host.local/api/version",
+		func(req *http.Request) (*http.Response, error) {
+			if err := req.Context().Err(); err != nil {
+				return nil, err
+			}
+			return newRawJSONResponse(200, `{"Version": "1.6.0"}`), nil
+		})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ctxClient := client.WithContext(ctx)
+
+	_, err := ctxClient.Version()
+	assert.NoError(t, err)
+
+	cancel()
+	_, err = ctxClient.Version()
+	assert.ErrorIs(t, err, context.Canceled)
+
+	// original client is not affected
+	_, err = client.Version()
+	assert.NoError(t, err)
+}
+
+func TestUnixSocket(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "aptly.sock")
+	listener, err := net.Listen("unix", socket)
+	assert.NoError(t, err)
+
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`[{"Name": "` + req.URL.Path + `"}]`))
+	})}
+	go func() { _ = server.Serve(listener) }()
+	defer server.Close()
+
+	client := NewClient("unix://" + socket)
+	repos, err := client.ReposList()
+	assert.NoError(t, err)
+	assert.Equal(t, []LocalRepo{{Name: "/api/repos"}}, repos)
+
+	client = NewClient("unix://" + filepath.Join(t.TempDir(), "missing.sock"))
+	_, err = client.ReposList()
+	assert.ErrorContains(t, err, "missing.sock")
+}
+
+func TestAPIErrorIs(t *testing.T) {
+	client := clientForTest(t, "http://host.local")
+
+	httpmock.RegisterResponder(http.MethodGet, "http://host.local/api/repos/missing",
+		newRawJSONResponder(404, `{"error": "local repo with name missing not found"}`))
+	httpmock.RegisterResponder(http.MethodPost, "http://host.local/api/repos",
+		newRawJSONResponder(400, `{"error": "local repo with name existing already exists"}`))
+	httpmock.RegisterResponder(http.MethodGet, "http://host.local/api/snapshots",
+		newRawJSONResponder(500, `{"error": "unable to open database: database is locked"}`))
+	httpmock.RegisterResponder(http.MethodGet, "http://host.local/api/publish",
+		httpmock.NewStringResponder(401, ""))
+
+	_, err := client.ReposShow("missing")
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.NotErrorIs(t, err, ErrConflict)
+	var apiErr *APIError
+	if assert.ErrorAs(t, err, &apiErr) {
+		assert.Equal(t, http.StatusNotFound, apiErr.StatusCode)
+		assert.Equal(t, http.MethodGet, apiErr.Method)
+		assert.Equal(t, "/api/repos/missing", apiErr.Path)
+	}
+
+	_, err = client.ReposCreate("existing", RepoCreateOptions{})
+	assert.ErrorIs(t, err, ErrConflict)
+	assert.NotErrorIs(t, err, ErrNotFound)
+
+	httpmock.RegisterResponder(http.MethodPost, "http://host.local/api/repos",
+		newRawJSONResponder(400, `{"error": "local repo with name locked already exists"}`))
+	_, err = client.ReposCreate("locked", RepoCreateOptions{})
+	assert.ErrorIs(t, err, ErrConflict)
+	assert.NotErrorIs(t, err, ErrLocked)
+
+	_, err = client.SnapshotList()
+	assert.ErrorIs(t, err, ErrLocked)
+
+	_, err = client.PublishList()
+	assert.ErrorIs(t, err, ErrUnauthorized)
+	assert.EqualError(t, err, "GET /api/publish: unexpected response code 401")
+}
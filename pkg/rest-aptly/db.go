@@ -0,0 +1,78 @@
+package aptly
+
+// DbCleanup remove unreferenced packages and files from the database and package pool
+func (c *Client) DbCleanup() error {
+	req := c.post("api/db/cleanup")
+
+	return c.send(req)
+}
+
+// DbCleanupAsync like DbCleanup but runs as task on the server
+func (c *Client) DbCleanupAsync() (Task, error) {
+	return c.sendAsync(c.post("api/db/cleanup"))
+}
+
+// DbUnreferencedPackages list packages not referenced by any repo, mirror or snapshot (Aptly >= 1.6.0)
+func (c *Client) DbUnreferencedPackages() ([]Package, error) {
+	// older servers can't search all packages, only referenced packages are found
+	if err := c.requireVersion("DbUnreferencedPackages", aptly160); err != nil {
+		return nil, err
+	}
+
+	// the REST API has no dry run of DbCleanup, unreferenced files in the package pool are not covered
+	all, err := c.PackagesSearch("", false)
+	if err != nil {
+		return nil, err
+	}
+
+	referenced := make(map[string]struct{})
+	addRefs := func(pkgs []Package) {
+		for _, pkg := range pkgs {
+			referenced[pkg.Key] = struct{}{}
+		}
+	}
+
+	repos, err := c.ReposList()
+	if err != nil {
+		return nil, err
+	}
+	for _, repo := range repos {
+		pkgs, err := c.ReposListPackages(repo.Name, ListPackagesOptions{})
+		if err != nil {
+			return nil, err
+		}
+		addRefs(pkgs)
+	}
+
+	mirrors, err := c.MirrorList()
+	if err != nil {
+		return nil, err
+	}
+	for _, mirror := range mirrors {
+		pkgs, err := c.MirrorPackages(mirror.Name, ListPackagesOptions{})
+		if err != nil {
+			return nil, err
+		}
+		addRefs(pkgs)
+	}
+
+	snaps, err := c.SnapshotList()
+	if err != nil {
+		return nil, err
+	}
+	for _, snap := range snaps {
+		pkgs, err := c.SnapshotPackages(snap.Name, ListPackagesOptions{})
+		if err != nil {
+			return nil, err
+		}
+		addRefs(pkgs)
+	}
+
+	unreferenced := []Package{}
+	for _, pkg := range all {
+		if _, ok := referenced[pkg.Key]; !ok {
+			unreferenced = append(unreferenced, pkg)
+		}
+	}
+	return unreferenced, nil
+}
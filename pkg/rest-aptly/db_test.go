@@ -0,0 +1,73 @@
+package aptly
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDbCleanup(t *testing.T) {
+	client := clientForTest(t, "http://host.local")
+
+	httpmock.RegisterResponderWithQuery(http.MethodPost, "http://host.local/api/db/cleanup", map[string]string{},
+		newRawJSONResponder(200, `{}`))
+	httpmock.RegisterResponderWithQuery(http.MethodPost, "http://host.local/api/db/cleanup", map[string]string{"_async": "true"},
+		newRawJSONResponder(202, `{"ID": 1, "Name": "Clean up db", "State": 0}`))
+
+	assert.NoError(t, client.DbCleanup())
+
+	task, err := client.DbCleanupAsync()
+	assert.NoError(t, err)
+	assert.Equal(t, Task{ID: 1, Name: "Clean up db", State: TaskIdle}, task)
+}
+
+func TestDbUnreferencedPackages(t *testing.T) {
+	client := clientForTest(t, "http://host.local")
+
+	httpmock.RegisterResponder(http.MethodGet, "http://host.local/api/packages",
+		newRawJSONResponder(200, `[
+			"Pamd64 nano 7.2-1+deb12u1 c5d2ac1639544e75",
+			"Psource hello 3.0.0-2 571d33f41765ddba",
+			"Pamd64 hello 3.0.0-2 96e8a0deaf8fc95f",
+			"Pamd64 hello-dbgsym 3.0.0-2 185cc47ca86a934c"
+		]`))
+	httpmock.RegisterResponder(http.MethodGet, "http://host.local/api/repos",
+		newRawJSONResponder(200, `[{"Name": "repo"}]`))
+	httpmock.RegisterResponder(http.MethodGet, "http://host.local/api/repos/repo/packages",
+		newRawJSONResponder(200, `["Pamd64 hello 3.0.0-2 96e8a0deaf8fc95f"]`))
+	httpmock.RegisterResponder(http.MethodGet, "http://host.local/api/mirrors",
+		newRawJSONResponder(200, `[]`))
+	httpmock.RegisterResponder(http.MethodGet, "http://host.local/api/snapshots",
+		newRawJSONResponder(200, `[{"Name": "snap"}]`))
+	httpmock.RegisterResponder(http.MethodGet, "http://host.local/api/snapshots/snap/packages",
+		newRawJSONResponder(200, `["Psource hello 3.0.0-2 571d33f41765ddba"]`))
+
+	pkgs, err := client.DbUnreferencedPackages()
+	assert.NoError(t, err)
+	assert.Equal(t, []Package{
+		{
+			Key:          "Pamd64 nano 7.2-1+deb12u1 c5d2ac1639544e75",
+			Architecture: "amd64",
+			FilesHash:    "c5d2ac1639544e75",
+			Version:      ver("7.2-1+deb12u1"),
+			Package:      "nano",
+		},
+		{
+			Key:          "Pamd64 hello-dbgsym 3.0.0-2 185cc47ca86a934c",
+			Architecture: "amd64",
+			FilesHash:    "185cc47ca86a934c",
+			Version:      ver("3.0.0-2"),
+			Package:      "hello-dbgsym",
+		},
+	}, pkgs)
+}
+
+func TestDbUnreferencedPackagesUnsupported(t *testing.T) {
+	client := oldClientForTest(t)
+
+	_, err := client.DbUnreferencedPackages()
+	assert.ErrorIs(t, err, ErrUnsupportedByServer)
+	assert.Equal(t, 1, httpmock.GetTotalCallCount())
+}
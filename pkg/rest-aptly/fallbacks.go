@@ -0,0 +1,183 @@
+package aptly
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"pault.ag/go/debian/version"
+)
+
+// Client side emulation of methods missing on servers older than aptly 1.6.0
+
+// SetFallbacks enable or disable emulation of methods the server is too old for, enabled by default
+//
+// When disabled these methods fail with ErrUnsupportedByServer
+func (c *Client) SetFallbacks(enabled bool) {
+	c.noFallbacks = !enabled
+}
+
+// useFallback true if the server is older than required and emulation is enabled
+func (c *Client) useFallback(feature string, required string) (bool, error) {
+	err := c.requireVersion(feature, required)
+	if err == nil {
+		return false, nil
+	}
+	if c.noFallbacks {
+		return false, err
+	}
+	if _, ok := err.(*UnsupportedByServerError); ok {
+		return true, nil
+	}
+	return false, err
+}
+
+// packagesSearchFallback search packages of all local repos, mirrors and snapshots
+//
+// unlike the server, packages not referenced by anything are not found
+func (c *Client) packagesSearchFallback(query string, detailed bool) ([]Package, error) {
+	opts := ListPackagesOptions{Query: query, Detailed: detailed}
+	found := make(map[string]struct{})
+	packages := []Package{}
+
+	add := func(pkgs []Package) {
+		for _, pkg := range pkgs {
+			if _, ok := found[pkg.Key]; !ok {
+				found[pkg.Key] = struct{}{}
+				packages = append(packages, pkg)
+			}
+		}
+	}
+
+	repos, err := c.ReposList()
+	if err != nil {
+		return nil, err
+	}
+	for _, repo := range repos {
+		pkgs, err := c.ReposListPackages(repo.Name, opts)
+		if err != nil {
+			return nil, err
+		}
+		add(pkgs)
+	}
+
+	mirrors, err := c.MirrorList()
+	if err != nil {
+		return nil, err
+	}
+	for _, mirror := range mirrors {
+		pkgs, err := c.MirrorPackages(mirror.Name, opts)
+		if err != nil {
+			return nil, err
+		}
+		add(pkgs)
+	}
+
+	snaps, err := c.SnapshotList()
+	if err != nil {
+		return nil, err
+	}
+	for _, snap := range snaps {
+		pkgs, err := c.SnapshotPackages(snap.Name, opts)
+		if err != nil {
+			return nil, err
+		}
+		add(pkgs)
+	}
+
+	return packages, nil
+}
+
+// publishShowFallback find the published repository in the list of all published repositories
+func (c *Client) publishShowFallback(distribution string, prefix string) (PublishedList, error) {
+	lists, err := c.PublishList()
+	if err != nil {
+		return PublishedList{}, err
+	}
+
+	target := ParsePublishTarget(prefix)
+	for _, list := range lists {
+		if list.Distribution == distribution && list.Target() == target {
+			return list, nil
+		}
+	}
+
+	msg := fmt.Sprintf("published repo with prefix/distribution %s/%s not found", target, distribution)
+	return PublishedList{}, &APIError{ErrorMsg: &msg, StatusCode: http.StatusNotFound, Method: http.MethodGet, Path: "/api/publish"}
+}
+
+// snapshotMergeFallback merge package refs like aptly does and create the snapshot from them
+func (c *Client) snapshotMergeFallback(destination string, sources []string, opts SnapshotMergeOptions) (Snapshot, error) {
+	lists := make([][]Package, 0, len(sources))
+	for _, source := range sources {
+		pkgs, err := c.SnapshotPackages(source, ListPackagesOptions{})
+		if err != nil {
+			return Snapshot{}, err
+		}
+		lists = append(lists, pkgs)
+	}
+
+	refs := mergePackageRefs(lists, opts)
+
+	quoted := make([]string, 0, len(sources))
+	for _, source := range sources {
+		quoted = append(quoted, fmt.Sprintf("'%s'", source))
+	}
+
+	return c.SnapshotCreate(destination, SnapshotCreateOptions{
+		Description:     "Merged from sources: " + strings.Join(quoted, ", "),
+		PackageRefs:     refs,
+		SourceSnapshots: sources,
+	})
+}
+
+// mergePackageRefs merge package lists in order
+//
+// by default packages with the same name and architecture in later lists replace earlier ones,
+// NoRemove keeps all packages and Latest keeps only the highest version
+func mergePackageRefs(lists [][]Package, opts SnapshotMergeOptions) []string {
+	type nameArch struct {
+		name string
+		arch string
+	}
+	merged := make(map[nameArch][]Package)
+	order := []nameArch{}
+
+	for _, list := range lists {
+		replaced := make(map[nameArch]bool)
+		for _, pkg := range list {
+			key := nameArch{name: pkg.Package, arch: pkg.Architecture}
+			existing, ok := merged[key]
+			if !ok {
+				order = append(order, key)
+			}
+			if ok && !opts.NoRemove && !opts.Latest && !replaced[key] {
+				existing = nil
+			}
+			replaced[key] = true
+			if !slices.ContainsFunc(existing, func(p Package) bool { return p.Key == pkg.Key }) {
+				existing = append(existing, pkg)
+			}
+			merged[key] = existing
+		}
+	}
+
+	refs := []string{}
+	for _, key := range order {
+		pkgs := merged[key]
+		if opts.Latest {
+			latest := pkgs[0]
+			for _, pkg := range pkgs[1:] {
+				if version.Compare(pkg.Version, latest.Version) > 0 {
+					latest = pkg
+				}
+			}
+			pkgs = []Package{latest}
+		}
+		for _, pkg := range pkgs {
+			refs = append(refs, pkg.Key)
+		}
+	}
+	return refs
+}
@@ -0,0 +1,132 @@
+package aptly
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/maxatome/go-testdeep/td"
+	"github.com/maxatome/tdhttpmock"
+	"github.com/stretchr/testify/assert"
+)
+
+// activate httpmock for an aptly 1.5.0 server
+func oldClientForTest(t *testing.T) *Client {
+	client := clientForTest(t, "http://host.local")
+	httpmock.RegisterResponder(http.MethodGet, "http://host.local/api/version", newRawJSONResponder(200, `{"Version": "1.5.0"}`))
+	return client
+}
+
+func TestPackagesSearchFallback(t *testing.T) {
+	client := oldClientForTest(t)
+
+	httpmock.RegisterResponder(http.MethodGet, "http://host.local/api/repos",
+		newRawJSONResponder(200, `[{"Name": "repo"}]`))
+	httpmock.RegisterResponderWithQuery(http.MethodGet, "http://host.local/api/repos/repo/packages",
+		map[string]string{"q": "hello"},
+		newRawJSONResponder(200, `["Pamd64 hello 3.0.0-2 96e8a0deaf8fc95f"]`))
+	httpmock.RegisterResponder(http.MethodGet, "http://host.local/api/mirrors",
+		newRawJSONResponder(200, `[]`))
+	httpmock.RegisterResponder(http.MethodGet, "http://host.local/api/snapshots",
+		newRawJSONResponder(200, `[{"Name": "snap"}]`))
+	httpmock.RegisterResponderWithQuery(http.MethodGet, "http://host.local/api/snapshots/snap/packages",
+		map[string]string{"q": "hello"},
+		newRawJSONResponder(200, testPkgsSimple2.JSON))
+
+	pkgs, err := client.PackagesSearch("hello", false)
+	assert.NoError(t, err)
+	assert.Equal(t, testPkgsSimple2.Pkgs, pkgs)
+}
+
+func TestPublishShowFallback(t *testing.T) {
+	client := oldClientForTest(t)
+
+	httpmock.RegisterResponder(http.MethodGet, "http://host.local/api/publish",
+		newRawJSONResponder(200, `
+[
+	{"Distribution": "bookworm", "Prefix": "repo", "Path": "repo/bookworm", "SourceKind": "local"},
+	{"Distribution": "trixie", "Prefix": ".", "Path": "./trixie", "SourceKind": "local"}
+]
+	`))
+
+	list, err := client.PublishShow("trixie", "")
+	assert.NoError(t, err)
+	assert.Equal(t, PublishedList{Distribution: "trixie", Prefix: ".", Path: "./trixie", SourceKind: "local"}, list)
+
+	list, err = client.PublishShow("bookworm", "repo")
+	assert.NoError(t, err)
+	assert.Equal(t, "repo/bookworm", list.Path)
+
+	_, err = client.PublishShow("bookworm", "other")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestSnapshotMergeFallback(t *testing.T) {
+	client := oldClientForTest(t)
+
+	httpmock.RegisterResponder(http.MethodGet, "http://host.local/api/snapshots/first/packages",
+		newRawJSONResponder(200, `["Pamd64 hello 3.0.0-2 96e8a0deaf8fc95f", "Pamd64 nano 7.2-1 c5d2ac1639544e75"]`))
+	httpmock.RegisterResponder(http.MethodGet, "http://host.local/api/snapshots/second/packages",
+		newRawJSONResponder(200, `["Pamd64 hello 2.0.0-1 185cc47ca86a934c"]`))
+	httpmock.RegisterMatcherResponder(http.MethodPost, "http://host.local/api/snapshots",
+		tdhttpmock.JSONBody(td.JSON(`
+{
+	"Name": "merged",
+	"Description": "Merged from sources: 'first', 'second'",
+	"PackageRefs": ["Pamd64 hello 2.0.0-1 185cc47ca86a934c", "Pamd64 nano 7.2-1 c5d2ac1639544e75"],
+	"SourceSnapshots": ["first", "second"]
+}
+		`)),
+		newRawJSONResponder(201, `{"Name": "merged"}`))
+
+	snap, err := client.SnapshotMerge("merged", []string{"first", "second"}, SnapshotMergeOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, "merged", snap.Name)
+}
+
+func TestMergePackageRefs(t *testing.T) {
+	pkg := func(key string) Package {
+		p, err := PackageFromKey(key)
+		assert.NoError(t, err)
+		return p
+	}
+	first := []Package{
+		pkg("Pamd64 hello 3.0.0-2 96e8a0deaf8fc95f"),
+		pkg("Pamd64 nano 7.2-1 c5d2ac1639544e75"),
+		pkg("Pamd64 nano 7.2-2 a5d2ac1639544e75"),
+	}
+	second := []Package{
+		pkg("Pamd64 hello 3.0.0-10 185cc47ca86a934c"),
+		pkg("Parm64 hello 3.0.0-1 285cc47ca86a934c"),
+	}
+
+	t.Run("override", func(t *testing.T) {
+		refs := mergePackageRefs([][]Package{first, second}, SnapshotMergeOptions{})
+		assert.Equal(t, []string{
+			"Pamd64 hello 3.0.0-10 185cc47ca86a934c",
+			"Pamd64 nano 7.2-1 c5d2ac1639544e75",
+			"Pamd64 nano 7.2-2 a5d2ac1639544e75",
+			"Parm64 hello 3.0.0-1 285cc47ca86a934c",
+		}, refs)
+	})
+
+	t.Run("no remove", func(t *testing.T) {
+		refs := mergePackageRefs([][]Package{first, second}, SnapshotMergeOptions{NoRemove: true})
+		assert.Equal(t, []string{
+			"Pamd64 hello 3.0.0-2 96e8a0deaf8fc95f",
+			"Pamd64 hello 3.0.0-10 185cc47ca86a934c",
+			"Pamd64 nano 7.2-1 c5d2ac1639544e75",
+			"Pamd64 nano 7.2-2 a5d2ac1639544e75",
+			"Parm64 hello 3.0.0-1 285cc47ca86a934c",
+		}, refs)
+	})
+
+	t.Run("latest", func(t *testing.T) {
+		refs := mergePackageRefs([][]Package{second, first}, SnapshotMergeOptions{Latest: true})
+		assert.Equal(t, []string{
+			"Pamd64 hello 3.0.0-10 185cc47ca86a934c",
+			"Parm64 hello 3.0.0-1 285cc47ca86a934c",
+			"Pamd64 nano 7.2-2 a5d2ac1639544e75",
+		}, refs)
+	})
+}
@@ -1,6 +1,10 @@
 package aptly
 
-import "fmt"
+import (
+	"context"
+	"os"
+	"path/filepath"
+)
 
 func (c *Client) FilesListDirs() ([]string, error) {
 	var dirs []string
@@ -21,18 +25,54 @@ func (c *Client) FilesListFiles(dir string) ([]string, error) {
 	return dirs, c.send(req)
 }
 
+// FilesUpload upload local files to dir, returns the uploaded files
 func (c *Client) FilesUpload(dir string, files []string) ([]string, error) {
-	var uploaded []string
+	uploads := make([]UploadFile, 0, len(files))
+	for _, file := range files {
+		upload, err := openUploadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		defer upload.Reader.(*os.File).Close()
+
+		uploads = append(uploads, upload)
+	}
+
+	return c.FilesUploadReaders(dir, uploads)
+}
+
+func openUploadFile(file string) (UploadFile, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return UploadFile{}, err
+	}
 
-	fileMap := make(map[string]string)
-	for i, file := range files {
-		fileMap[fmt.Sprintf("file%d", i)] = file
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return UploadFile{}, err
 	}
+	return UploadFile{Name: filepath.Base(file), Reader: f, Size: fi.Size()}, nil
+}
+
+// FilesUploadReaders upload named readers to dir, the content is streamed to the server
+func (c *Client) FilesUploadReaders(dir string, files []UploadFile) ([]string, error) {
+	return c.filesUpload(dir, files, c.uploadProgress)
+}
+
+func (c *Client) filesUpload(dir string, files []UploadFile, progress func(UploadProgress)) ([]string, error) {
+	var uploaded []string
+
+	u := newUpload(files, progress)
 
 	req := c.post("api/files/{dir}").
 		SetPathParam("dir", dir).
-		SetResult(&uploaded).
-		SetFiles(fileMap)
+		SetResult(&uploaded)
+	req.SetContext(context.WithValue(req.Context(), uploadKey{}, u))
+	if u.seekable() {
+		// uploading the same file again replaces it
+		markIdempotent(req)
+	}
 
 	return uploaded, c.send(req)
 }
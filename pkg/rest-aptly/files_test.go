@@ -3,9 +3,17 @@ package aptly
 import (
 	"bytes"
 	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
 	"net/http"
 	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/jarcoal/httpmock"
 	"github.com/stretchr/testify/assert"
@@ -86,6 +94,233 @@ func TestFilesUpload(t *testing.T) {
 	assert.ElementsMatch(t, list, []string{"file0", "extra", "another"})
 }
 
+func TestFilesUploadReaders(t *testing.T) {
+	client := clientForTest(t, "http://host.local")
+
+	httpmock.RegisterResponder(http.MethodPost, "http://host.local/api/files/dirTest",
+		func(req *http.Request) (*http.Response, error) {
+			err := req.ParseMultipartForm(1024 * 1024 * 4)
+			if err != nil {
+				return nil, err
+			}
+			for name, data := range map[string]string{"file0": "first file", "file1": "second"} {
+				equal, err := formFileEqual(req, name, []byte(data))
+				if err != nil {
+					return nil, err
+				}
+				if !equal {
+					return httpmock.NewStringResponse(400, "not equal"), nil
+				}
+			}
+			return httpmock.NewJsonResponse(200, []string{"dirTest/a.deb", "dirTest/b.deb"})
+		})
+
+	var progress []UploadProgress
+	client.SetUploadProgress(func(p UploadProgress) {
+		progress = append(progress, p)
+	})
+
+	list, err := client.FilesUploadReaders("dirTest", []UploadFile{
+		{Name: "a.deb", Reader: strings.NewReader("first file"), Size: 10},
+		// not seekable and unknown size
+		{Name: "b.deb", Reader: io.MultiReader(strings.NewReader("second"))},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"dirTest/a.deb", "dirTest/b.deb"}, list)
+	assert.Equal(t, []UploadProgress{
+		{File: "a.deb", FileSent: 0, FileSize: 10, TotalSent: 0, TotalSize: 10},
+		{File: "a.deb", FileSent: 10, FileSize: 10, TotalSent: 10, TotalSize: 10},
+		{File: "b.deb", FileSent: 0, FileSize: 0, TotalSent: 10, TotalSize: 10},
+		{File: "b.deb", FileSent: 6, FileSize: 0, TotalSent: 16, TotalSize: 10},
+	}, progress)
+}
+
+func TestFilesUploadReadersRetry(t *testing.T) {
+	client := retryClientForTest(t)
+
+	httpmock.RegisterResponder(http.MethodPost, "http://host.local/api/files/dirTest",
+		httpmock.NewStringResponder(503, ""))
+
+	// not seekable, can not be sent again
+	_, err := client.FilesUploadReaders("dirTest", []UploadFile{
+		{Name: "a.deb", Reader: io.MultiReader(strings.NewReader("data"))},
+	})
+	assert.Error(t, err)
+	assert.Equal(t, 1, httpmock.GetTotalCallCount())
+
+	httpmock.ZeroCallCounters()
+	_, err = client.FilesUploadReaders("dirTest", []UploadFile{
+		{Name: "a.deb", Reader: strings.NewReader("data")},
+	})
+	assert.Error(t, err)
+	assert.Equal(t, 3, httpmock.GetTotalCallCount())
+}
+
+// stallingReader stalls in the third read, stalled is closed when the stall starts
+type stallingReader struct {
+	*bytes.Reader
+	reads   int
+	stalled chan struct{}
+}
+
+func (r *stallingReader) Read(p []byte) (int, error) {
+	r.reads++
+	if r.reads == 3 {
+		close(r.stalled)
+		time.Sleep(50 * time.Millisecond)
+	}
+	return r.Reader.Read(p)
+}
+
+func TestUploadRestartMidRead(t *testing.T) {
+	// larger than the buffer of the writer, the restart happens while the previous attempt reads the file
+	data := bytes.Repeat([]byte("0123456789abcdef"), 64*1024)
+	reader := &stallingReader{Reader: bytes.NewReader(data), stalled: make(chan struct{})}
+	u := newUpload([]UploadFile{{Name: "a.deb", Reader: reader, Size: int64(len(data))}}, nil)
+
+	body, _, err := u.open()
+	assert.NoError(t, err)
+	go func() {
+		_, _ = io.Copy(io.Discard, body)
+	}()
+	<-reader.stalled
+
+	body, contentType, err := u.open()
+	assert.NoError(t, err)
+	_, params, err := mime.ParseMediaType(contentType)
+	assert.NoError(t, err)
+	part, err := multipart.NewReader(body, params["boundary"]).NextPart()
+	assert.NoError(t, err)
+	sent, err := io.ReadAll(part)
+	assert.NoError(t, err)
+	assert.Equal(t, len(data), len(sent))
+	assert.True(t, bytes.Equal(data, sent))
+}
+
+func TestFilesUploadParallel(t *testing.T) {
+	client := clientForTest(t, "http://host.local")
+
+	dir := t.TempDir()
+	files := []string{}
+	for _, name := range []string{"a.deb", "b.deb", "broken.deb", "c.deb"} {
+		path := filepath.Join(dir, name)
+		assert.NoError(t, os.WriteFile(path, []byte("data of "+name), 0o644))
+		files = append(files, path)
+	}
+
+	var running, maxRunning atomic.Int32
+	httpmock.RegisterResponder(http.MethodPost, "http://host.local/api/files/dirTest",
+		func(req *http.Request) (*http.Response, error) {
+			n := running.Add(1)
+			defer running.Add(-1)
+			for {
+				m := maxRunning.Load()
+				if n <= m || maxRunning.CompareAndSwap(m, n) {
+					break
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+
+			err := req.ParseMultipartForm(1024 * 1024)
+			if err != nil {
+				return nil, err
+			}
+			_, header, err := req.FormFile("file0")
+			if err != nil {
+				return nil, err
+			}
+			if header.Filename == "broken.deb" {
+				return newRawJSONResponse(400, `{"error": "broken file"}`), nil
+			}
+			return httpmock.NewJsonResponse(200, []string{"dirTest/" + header.Filename})
+		})
+
+	var lastProgress UploadProgress
+	var mu sync.Mutex
+	client.SetUploadProgress(func(p UploadProgress) {
+		mu.Lock()
+		defer mu.Unlock()
+		if p.TotalSent >= lastProgress.TotalSent {
+			lastProgress = p
+		}
+	})
+
+	results, err := client.FilesUploadParallel("dirTest", files, ParallelUploadOptions{Parallel: 2})
+	assert.EqualError(t, err, "1 of 4 files failed to upload")
+	assert.Len(t, results, 4)
+	for i, result := range results {
+		assert.Equal(t, files[i], result.File)
+	}
+	assert.Equal(t, []string{"dirTest/a.deb"}, results[0].Uploaded)
+	assert.Equal(t, []string{"dirTest/c.deb"}, results[3].Uploaded)
+	assert.NoError(t, results[1].Err)
+	assert.EqualError(t, results[2].Err, "broken file")
+
+	assert.Equal(t, 4, httpmock.GetTotalCallCount())
+	assert.LessOrEqual(t, maxRunning.Load(), int32(2))
+
+	// 4 files with "data of x.deb" and "data of broken.deb"
+	assert.Equal(t, int64(3*13+18), lastProgress.TotalSize)
+	assert.Equal(t, lastProgress.TotalSize, lastProgress.TotalSent)
+}
+
+func TestFilesUploadParallelRetry(t *testing.T) {
+	client := retryClientForTest(t)
+
+	path := filepath.Join(t.TempDir(), "a.deb")
+	assert.NoError(t, os.WriteFile(path, []byte("data"), 0o644))
+
+	httpmock.RegisterResponder(http.MethodPost, "http://host.local/api/files/dirTest",
+		httpmock.ResponderFromMultipleResponses([]*http.Response{
+			httpmock.NewStringResponse(503, ""),
+			newRawJSONResponse(200, `["dirTest/a.deb"]`),
+		}))
+
+	results, err := client.FilesUploadParallel("dirTest", []string{path}, ParallelUploadOptions{Parallel: 4})
+	assert.NoError(t, err)
+	assert.Equal(t, []UploadResult{{File: path, Uploaded: []string{"dirTest/a.deb"}}}, results)
+	assert.Equal(t, 2, httpmock.GetTotalCallCount())
+}
+
+func TestFilesUploadParallelAttempts(t *testing.T) {
+	// without retry policy
+	client := clientForTest(t, "http://host.local")
+
+	dir := t.TempDir()
+	files := []string{}
+	for _, name := range []string{"a.deb", "b.deb"} {
+		path := filepath.Join(dir, name)
+		assert.NoError(t, os.WriteFile(path, []byte("data of "+name), 0o644))
+		files = append(files, path)
+	}
+
+	var failures sync.Map
+	httpmock.RegisterResponder(http.MethodPost, "http://host.local/api/files/dirTest",
+		func(req *http.Request) (*http.Response, error) {
+			err := req.ParseMultipartForm(1024 * 1024)
+			if err != nil {
+				return nil, err
+			}
+			_, header, err := req.FormFile("file0")
+			if err != nil {
+				return nil, err
+			}
+			// a.deb fails once, b.deb always
+			if _, failed := failures.LoadOrStore(header.Filename, true); !failed || header.Filename == "b.deb" {
+				return newRawJSONResponse(500, `{"error": "write failed"}`), nil
+			}
+			return httpmock.NewJsonResponse(200, []string{"dirTest/" + header.Filename})
+		})
+
+	results, err := client.FilesUploadParallel("dirTest", files, ParallelUploadOptions{Parallel: 2, Attempts: 3, WaitTime: time.Millisecond})
+	assert.EqualError(t, err, "1 of 2 files failed to upload")
+	assert.Equal(t, []string{"dirTest/a.deb"}, results[0].Uploaded)
+	assert.NoError(t, results[0].Err)
+	assert.EqualError(t, results[1].Err, "write failed")
+	// a.deb twice, b.deb three times
+	assert.Equal(t, 5, httpmock.GetTotalCallCount())
+}
+
 func TestFilesDeleteDir(t *testing.T) {
 	client := clientForTest(t, "http://host.local")
 
@@ -0,0 +1,28 @@
+package aptly
+
+const (
+	GraphLayoutHorizontal = "horizontal"
+	GraphLayoutVertical   = "vertical"
+)
+
+// Graph get the graph of relations between mirrors, local repos, snapshots and published repositories
+//
+// format is the graphviz output format like svg or png, requires graphviz on the server.
+// The dot source is returned for format dot or gv which works without graphviz.
+// layout is GraphLayoutHorizontal or GraphLayoutVertical, server default if empty
+func (c *Client) Graph(format string, layout string) ([]byte, error) {
+	params := make(map[string]string)
+	if layout != "" {
+		params["layout"] = layout
+	}
+
+	req := c.get("api/graph.{ext}").
+		SetPathParam("ext", format).
+		SetQueryParams(params)
+
+	resp, err := c.sendRaw(req)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body(), nil
+}
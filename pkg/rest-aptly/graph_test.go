@@ -0,0 +1,31 @@
+package aptly
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGraph(t *testing.T) {
+	client := clientForTest(t, "http://host.local")
+
+	httpmock.RegisterResponderWithQuery(http.MethodGet, "http://host.local/api/graph.dot", map[string]string{},
+		httpmock.NewStringResponder(200, "digraph aptly {}"))
+	httpmock.RegisterResponderWithQuery(http.MethodGet, "http://host.local/api/graph.svg", map[string]string{"layout": "vertical"},
+		httpmock.NewStringResponder(200, "<svg></svg>"))
+	httpmock.RegisterResponder(http.MethodGet, "http://host.local/api/graph.png",
+		newRawJSONResponder(500, `{"error": "unable to execute dot"}`))
+
+	dot, err := client.Graph("dot", "")
+	assert.NoError(t, err)
+	assert.Equal(t, "digraph aptly {}", string(dot))
+
+	svg, err := client.Graph("svg", GraphLayoutVertical)
+	assert.NoError(t, err)
+	assert.Equal(t, "<svg></svg>", string(svg))
+
+	_, err = client.Graph("png", "")
+	assert.ErrorContains(t, err, "unable to execute dot")
+}
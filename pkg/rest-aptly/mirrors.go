@@ -0,0 +1,204 @@
+package aptly
+
+// RemoteRepo is a mirror of a remote debian repository
+type RemoteRepo struct {
+	UUID string
+	// Human-readable name
+	Name string
+	// Root of Debian archive, URL
+	ArchiveRoot string
+	// Distribution name, e.g. bookworm
+	Distribution string
+	// List of components to fetch, if empty, then fetch all components
+	Components []string
+	// List of architectures to fetch, if empty, then fetch all architectures
+	Architectures []string
+	// Meta-information about repository, from the Release file
+	Meta map[string]string `json:",omitempty"`
+	// Last update date
+	LastDownloadDate string
+	// Package query applied to the mirror
+	Filter string
+	// include dependencies of filtered packages
+	FilterWithDeps bool
+	// mirror source packages
+	DownloadSources bool
+	// mirror .udeb packages
+	DownloadUdebs bool
+	// mirror installer files
+	DownloadInstaller bool
+	// skip check that requested components are listed in Release file
+	SkipComponentCheck bool
+	// skip check that requested architectures are listed in Release file
+	SkipArchitectureCheck bool
+	// 0 idle, 1 updating
+	Status int
+	// PID of the process updating the mirror
+	WorkerPID int
+}
+
+// MirrorList get the list of mirrors
+func (c *Client) MirrorList() ([]RemoteRepo, error) {
+	var mirrors []RemoteRepo
+
+	req := c.get("api/mirrors").
+		SetResult(&mirrors)
+
+	return mirrors, c.send(req)
+}
+
+// MirrorShow get mirror information
+func (c *Client) MirrorShow(name string) (RemoteRepo, error) {
+	var mirror RemoteRepo
+
+	req := c.get("api/mirrors/{name}").
+		SetPathParam("name", name).
+		SetResult(&mirror)
+
+	return mirror, c.send(req)
+}
+
+type MirrorCreateOptions struct {
+	// Distribution to mirror, e.g. bookworm, if empty the mirror is a flat repository
+	Distribution string `json:",omitempty"`
+	// Components to mirror, if empty all components are mirrored
+	Components []string `json:",omitempty"`
+	// Architectures to mirror, if empty all architectures are mirrored
+	Architectures []string `json:",omitempty"`
+	// gpg keyrings to use when verifying Release file
+	Keyrings []string `json:",omitempty"`
+	// package query applied to packages in the mirror
+	Filter string `json:",omitempty"`
+	// include dependencies of filtered packages
+	FilterWithDeps bool `json:",omitempty"`
+	// mirror source packages
+	DownloadSources bool `json:",omitempty"`
+	// mirror .udeb packages
+	DownloadUdebs bool `json:",omitempty"`
+	// mirror installer files
+	DownloadInstaller bool `json:",omitempty"`
+	// skip check that requested components are listed in Release file
+	SkipComponentCheck bool `json:",omitempty"`
+	// skip check that requested architectures are listed in Release file
+	SkipArchitectureCheck bool `json:",omitempty"`
+	// disable verification of Release file signatures
+	IgnoreSignatures bool `json:",omitempty"`
+}
+
+// MirrorCreate create new mirror of the remote repository at archiveURL
+func (c *Client) MirrorCreate(name string, archiveURL string, opts MirrorCreateOptions) (RemoteRepo, error) {
+	var mirror RemoteRepo
+
+	type createPayload struct {
+		Name       string
+		ArchiveURL string
+		MirrorCreateOptions
+	}
+
+	req := c.post("api/mirrors").
+		SetResult(&mirror).
+		SetBody(&createPayload{Name: name, ArchiveURL: archiveURL, MirrorCreateOptions: opts})
+
+	return mirror, c.send(req)
+}
+
+type MirrorEditOptions struct {
+	// new mirror name
+	Name string `json:",omitempty"`
+	// new archive url
+	ArchiveURL string `json:",omitempty"`
+	// package query applied to packages in the mirror
+	Filter *string `json:",omitempty"`
+	// include dependencies of filtered packages
+	FilterWithDeps *bool `json:",omitempty"`
+	// Architectures to mirror
+	Architectures []string `json:",omitempty"`
+	// Components to mirror
+	Components []string `json:",omitempty"`
+	// mirror source packages
+	DownloadSources *bool `json:",omitempty"`
+	// mirror .udeb packages
+	DownloadUdebs *bool `json:",omitempty"`
+	// skip check that requested components are listed in Release file
+	SkipComponentCheck *bool `json:",omitempty"`
+}
+
+// MirrorEdit change mirror settings
+//
+// Note: the aptly API applies changes as part of an update, so the mirror is downloaded again
+func (c *Client) MirrorEdit(name string, opts MirrorEditOptions) (RemoteRepo, error) {
+	var mirror RemoteRepo
+
+	req := c.put("api/mirrors/{name}").
+		SetPathParam("name", name).
+		SetResult(&mirror).
+		SetBody(&opts)
+
+	return mirror, c.send(req)
+}
+
+// MirrorRename change the name of the mirror
+//
+// Note: aptly has no API to only rename a mirror, the rename is part of an update, so the mirror is downloaded again
+func (c *Client) MirrorRename(name string, newName string) (RemoteRepo, error) {
+	return c.MirrorEdit(name, MirrorEditOptions{Name: newName})
+}
+
+type MirrorUpdateOptions struct {
+	// force update even if the mirror is locked by another update
+	ForceUpdate bool `json:",omitempty"`
+	// ignore checksum mismatches while downloading
+	IgnoreChecksums bool `json:",omitempty"`
+	// disable verification of Release file signatures
+	IgnoreSignatures bool `json:",omitempty"`
+	// do not download packages already present in the package pool
+	SkipExistingPackages bool `json:",omitempty"`
+	// gpg keyrings to use when verifying Release file
+	Keyrings []string `json:",omitempty"`
+	// max download tries till process fails with download error
+	MaxTries int `json:",omitempty"`
+	// limit download speed in kbytes/sec
+	DownloadLimit int64 `json:",omitempty"`
+	// downloader to use: default or grab
+	Downloader string `json:",omitempty"`
+}
+
+// MirrorUpdate download the latest state of the remote repository
+func (c *Client) MirrorUpdate(name string, opts MirrorUpdateOptions) (RemoteRepo, error) {
+	var mirror RemoteRepo
+
+	req := c.put("api/mirrors/{name}").
+		SetPathParam("name", name).
+		SetResult(&mirror).
+		SetBody(&opts)
+
+	return mirror, c.send(req)
+}
+
+// MirrorDrop delete the mirror
+func (c *Client) MirrorDrop(name string, force bool) error {
+	params := make(map[string]string)
+	if force {
+		params["force"] = "1"
+	}
+
+	req := c.delete("api/mirrors/{name}").
+		SetPathParam("name", name).
+		SetQueryParams(params)
+
+	return c.send(req)
+}
+
+// MirrorPackages get list of packages in the mirror
+func (c *Client) MirrorPackages(name string, opts ListPackagesOptions) ([]Package, error) {
+	params, err := opts.MakeParams()
+	if err != nil {
+		return nil, err
+	}
+
+	req := c.get("api/mirrors/{name}/packages").
+		SetPathParam("name", name).
+		SetQueryParams(params)
+
+	return sendPackagesRequest(req, opts.Detailed)
+}
@@ -0,0 +1,174 @@
+package aptly
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/maxatome/go-testdeep/td"
+	"github.com/maxatome/tdhttpmock"
+	"github.com/stretchr/testify/assert"
+)
+
+const testMirrorJSON = `
+{
+	"UUID": "2cb5985a-a23f-4a1f-8eb6-d5409193b4eb",
+	"Name": "bookworm-main",
+	"ArchiveRoot": "http://deb.debian.org/debian/",
+	"Distribution": "bookworm",
+	"Components": ["main"],
+	"Architectures": ["amd64"],
+	"LastDownloadDate": "0001-01-01T00:00:00Z",
+	"Meta": null,
+	"Filter": "nginx",
+	"Status": 0,
+	"WorkerPID": 0,
+	"FilterWithDeps": true,
+	"SkipComponentCheck": false,
+	"SkipArchitectureCheck": false,
+	"DownloadSources": false,
+	"DownloadUdebs": false,
+	"DownloadInstaller": false
+}
+`
+
+var testMirror = RemoteRepo{
+	UUID:             "2cb5985a-a23f-4a1f-8eb6-d5409193b4eb",
+	Name:             "bookworm-main",
+	ArchiveRoot:      "http://deb.debian.org/debian/",
+	Distribution:     "bookworm",
+	Components:       []string{"main"},
+	Architectures:    []string{"amd64"},
+	LastDownloadDate: "0001-01-01T00:00:00Z",
+	Filter:           "nginx",
+	FilterWithDeps:   true,
+}
+
+func TestMirrorList(t *testing.T) {
+	client := clientForTest(t, "http://host.local")
+
+	httpmock.RegisterResponder(http.MethodGet, "http://host.local/api/mirrors",
+		newRawJSONResponder(200, "["+testMirrorJSON+"]"))
+
+	mirrors, err := client.MirrorList()
+	assert.NoError(t, err)
+	assert.Equal(t, []RemoteRepo{testMirror}, mirrors)
+}
+
+func TestMirrorShow(t *testing.T) {
+	client := clientForTest(t, "http://host.local")
+
+	httpmock.RegisterResponder(http.MethodGet, "http://host.local/api/mirrors/bookworm-main",
+		newRawJSONResponder(200, testMirrorJSON))
+
+	mirror, err := client.MirrorShow("bookworm-main")
+	assert.NoError(t, err)
+	assert.Equal(t, testMirror, mirror)
+}
+
+func TestMirrorCreate(t *testing.T) {
+	client := clientForTest(t, "http://host.local")
+
+	httpmock.RegisterMatcherResponder(http.MethodPost, "http://host.local/api/mirrors",
+		tdhttpmock.JSONBody(td.JSON(`
+{
+	"Name": "bookworm-main",
+	"ArchiveURL": "http://deb.debian.org/debian/",
+	"Distribution": "bookworm",
+	"Components": ["main"],
+	"Architectures": ["amd64"],
+	"Filter": "nginx",
+	"FilterWithDeps": true
+}
+		`)),
+		newRawJSONResponder(200, testMirrorJSON))
+
+	mirror, err := client.MirrorCreate("bookworm-main", "http://deb.debian.org/debian/", MirrorCreateOptions{
+		Distribution:   "bookworm",
+		Components:     []string{"main"},
+		Architectures:  []string{"amd64"},
+		Filter:         "nginx",
+		FilterWithDeps: true,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, testMirror, mirror)
+}
+
+func TestMirrorEdit(t *testing.T) {
+	client := clientForTest(t, "http://host.local")
+
+	httpmock.RegisterMatcherResponder(http.MethodPut, "http://host.local/api/mirrors/old",
+		tdhttpmock.JSONBody(td.JSON(`
+{
+	"Name": "bookworm-main",
+	"Filter": "",
+	"DownloadSources": false
+}
+		`)),
+		newRawJSONResponder(200, testMirrorJSON))
+
+	mirror, err := client.MirrorEdit("old", MirrorEditOptions{Name: "bookworm-main", Filter: ptr(""), DownloadSources: ptr(false)})
+	assert.NoError(t, err)
+	assert.Equal(t, testMirror, mirror)
+}
+
+func TestMirrorRename(t *testing.T) {
+	client := clientForTest(t, "http://host.local")
+
+	// only the name is sent, all other settings of the mirror are kept
+	httpmock.RegisterMatcherResponder(http.MethodPut, "http://host.local/api/mirrors/old",
+		tdhttpmock.JSONBody(td.JSON(`{"Name": "bookworm-main"}`)),
+		newRawJSONResponder(200, testMirrorJSON))
+
+	mirror, err := client.MirrorRename("old", "bookworm-main")
+	assert.NoError(t, err)
+	assert.Equal(t, testMirror, mirror)
+}
+
+func TestMirrorUpdate(t *testing.T) {
+	client := clientForTest(t, "http://host.local")
+
+	httpmock.RegisterMatcherResponder(http.MethodPut, "http://host.local/api/mirrors/bookworm-main",
+		tdhttpmock.JSONBody(td.JSON(`
+{
+	"ForceUpdate": true,
+	"IgnoreSignatures": true,
+	"MaxTries": 3
+}
+		`)),
+		newRawJSONResponder(200, testMirrorJSON))
+
+	mirror, err := client.MirrorUpdate("bookworm-main", MirrorUpdateOptions{ForceUpdate: true, IgnoreSignatures: true, MaxTries: 3})
+	assert.NoError(t, err)
+	assert.Equal(t, testMirror, mirror)
+}
+
+func TestMirrorDrop(t *testing.T) {
+	client := clientForTest(t, "http://host.local")
+
+	httpmock.RegisterResponderWithQuery(http.MethodDelete, "http://host.local/api/mirrors/simple", map[string]string{},
+		httpmock.NewStringResponder(200, "").Once())
+	httpmock.RegisterResponderWithQuery(http.MethodDelete, "http://host.local/api/mirrors/forced", map[string]string{"force": "1"},
+		httpmock.NewStringResponder(200, "").Once())
+
+	assert.NoError(t, client.MirrorDrop("simple", false))
+	assert.NoError(t, client.MirrorDrop("forced", true))
+}
+
+func TestMirrorPackages(t *testing.T) {
+	client := clientForTest(t, "http://host.local")
+
+	httpmock.RegisterResponder(http.MethodGet, "http://host.local/api/mirrors/bookworm-main/packages",
+		newRawJSONResponder(200, testPkgsSimple1.JSON))
+	httpmock.RegisterResponderWithQuery(http.MethodGet, "http://host.local/api/mirrors/bookworm-main/packages",
+		map[string]string{"format": "details"},
+		newRawJSONResponder(200, testPkgsDetailed.JSON))
+
+	pkgs, err := client.MirrorPackages("bookworm-main", ListPackagesOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, testPkgsSimple1.Pkgs, pkgs)
+
+	pkgs, err = client.MirrorPackages("bookworm-main", ListPackagesOptions{Detailed: true})
+	assert.NoError(t, err)
+	assert.Equal(t, testPkgsDetailed.Pkgs, pkgs)
+}
@@ -5,8 +5,11 @@ import (
 	"errors"
 	"fmt"
 	"regexp"
+	"strconv"
 
 	"github.com/go-resty/resty/v2"
+	"pault.ag/go/debian/dependency"
+	"pault.ag/go/debian/version"
 )
 
 // ListPackagesOptions is used in SnapshotPackages(Detailed) and RepoPackages(Detailed)
@@ -48,7 +51,7 @@ type Package struct {
 	// unique package identifier
 	Key          string
 	FilesHash    string
-	Version      string
+	Version      version.Version
 	Architecture string
 
 	// only available when details format used
@@ -56,11 +59,165 @@ type Package struct {
 	ShortKey string
 	// package name
 	Package string
-	// List of virtual packages this package provides
-	Provides *[]string
-
+	// source package name, if different from package name
 	Source *string
-	//Extras map[string]string
+
+	Maintainer    string
+	Section       string
+	Priority      string
+	Homepage      string
+	Description   string
+	Filename      string
+	MultiArch     string
+	Size          int64
+	InstalledSize int64
+
+	MD5sum string
+	SHA1   string
+	SHA256 string
+	SHA512 string
+
+	// relations, nil if not set
+	Depends           *dependency.Dependency
+	PreDepends        *dependency.Dependency
+	Recommends        *dependency.Dependency
+	Suggests          *dependency.Dependency
+	Enhances          *dependency.Dependency
+	Breaks            *dependency.Dependency
+	Conflicts         *dependency.Dependency
+	Replaces          *dependency.Dependency
+	Provides          *dependency.Dependency
+	BuildDepends      *dependency.Dependency
+	BuildDependsIndep *dependency.Dependency
+
+	// all other fields of the stanza, also fields which could not be parsed
+	Extras map[string]string
+}
+
+// package fields by their name in the stanza
+func (p *Package) stringFields() map[string]*string {
+	return map[string]*string{
+		"Key":          &p.Key,
+		"FilesHash":    &p.FilesHash,
+		"Architecture": &p.Architecture,
+		"ShortKey":     &p.ShortKey,
+		"Package":      &p.Package,
+		"Maintainer":   &p.Maintainer,
+		"Section":      &p.Section,
+		"Priority":     &p.Priority,
+		"Homepage":     &p.Homepage,
+		"Description":  &p.Description,
+		"Filename":     &p.Filename,
+		"Multi-Arch":   &p.MultiArch,
+		"MD5sum":       &p.MD5sum,
+		"SHA1":         &p.SHA1,
+		"SHA256":       &p.SHA256,
+		"SHA512":       &p.SHA512,
+	}
+}
+
+func (p *Package) intFields() map[string]*int64 {
+	return map[string]*int64{
+		"Size":           &p.Size,
+		"Installed-Size": &p.InstalledSize,
+	}
+}
+
+func (p *Package) relationFields() map[string]**dependency.Dependency {
+	return map[string]**dependency.Dependency{
+		"Depends":             &p.Depends,
+		"Pre-Depends":         &p.PreDepends,
+		"Recommends":          &p.Recommends,
+		"Suggests":            &p.Suggests,
+		"Enhances":            &p.Enhances,
+		"Breaks":              &p.Breaks,
+		"Conflicts":           &p.Conflicts,
+		"Replaces":            &p.Replaces,
+		"Provides":            &p.Provides,
+		"Build-Depends":       &p.BuildDepends,
+		"Build-Depends-Indep": &p.BuildDependsIndep,
+	}
+}
+
+// UnmarshalJSON decode the package stanza, fields that can't be parsed are kept in Extras
+func (p *Package) UnmarshalJSON(data []byte) error {
+	var stanza map[string]string
+	if err := json.Unmarshal(data, &stanza); err != nil {
+		return err
+	}
+
+	*p = Package{}
+	strs := p.stringFields()
+	ints := p.intFields()
+	relations := p.relationFields()
+
+	for field, value := range stanza {
+		if ptr, ok := strs[field]; ok {
+			*ptr = value
+			continue
+		}
+		if ptr, ok := ints[field]; ok {
+			if i, err := strconv.ParseInt(value, 10, 64); err == nil {
+				*ptr = i
+				continue
+			}
+		}
+		if ptr, ok := relations[field]; ok {
+			if dep, err := dependency.Parse(value); err == nil {
+				*ptr = dep
+				continue
+			}
+		}
+		switch field {
+		case "Version":
+			// an invalid version is kept as Extras["Version"] to not fail the decoding of all packages
+			if ver, err := version.Parse(value); err == nil {
+				p.Version = ver
+				continue
+			}
+		case "Source":
+			p.Source = &value
+			continue
+		}
+
+		if p.Extras == nil {
+			p.Extras = make(map[string]string)
+		}
+		p.Extras[field] = value
+	}
+	return nil
+}
+
+// MarshalJSON encode the package as stanza like aptly does
+func (p Package) MarshalJSON() ([]byte, error) {
+	stanza := make(map[string]string)
+	for field, value := range p.Extras {
+		stanza[field] = value
+	}
+
+	for field, ptr := range p.stringFields() {
+		if *ptr != "" {
+			stanza[field] = *ptr
+		}
+	}
+	for field, ptr := range p.intFields() {
+		if *ptr != 0 {
+			stanza[field] = strconv.FormatInt(*ptr, 10)
+		}
+	}
+	for field, ptr := range p.relationFields() {
+		if *ptr != nil {
+			stanza[field] = (*ptr).String()
+		}
+	}
+	if !p.Version.Empty() {
+		stanza["Version"] = p.Version.String()
+	}
+	if p.Source != nil {
+		stanza["Source"] = *p.Source
+	}
+
+	return json.Marshal(stanza)
 }
 
 var packageRegex = regexp.MustCompile(`^(\S*)P(\S+)\s(\S+)\s(\S+)\s(\S+)$`)
@@ -72,8 +229,12 @@ func PackageFromKey(key string) (Package, error) {
 	if matched == nil {
 		return Package{}, fmt.Errorf("could not match '%s'", key)
 	}
+	ver, err := version.Parse(matched[4])
+	if err != nil {
+		return Package{}, fmt.Errorf("invalid version in '%s': %w", key, err)
+	}
 	// ignore prefix matched[1] for now
-	return Package{Key: key, Architecture: matched[2], Package: matched[3], Version: matched[4], FilesHash: matched[5]}, nil
+	return Package{Key: key, Architecture: matched[2], Package: matched[3], Version: ver, FilesHash: matched[5]}, nil
 }
 
 func sendPackagesRequest(req *resty.Request, detailed bool) ([]Package, error) {
@@ -114,8 +275,14 @@ func sendPackagesRequest(req *resty.Request, detailed bool) ([]Package, error) {
 
 // PackagesSearch returns list of packages
 //
-// since Aptly 1.6.0
+// since Aptly 1.6.0, emulated for older servers by searching all repos, mirrors and snapshots
 func (c *Client) PackagesSearch(query string, detailed bool) ([]Package, error) {
+	fallback, err := c.useFallback("PackagesSearch", aptly160)
+	if err != nil {
+		return nil, err
+	} else if fallback {
+		return c.packagesSearchFallback(query, detailed)
+	}
 
 	params := make(map[string]string)
 	if query != "" {
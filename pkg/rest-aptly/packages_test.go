@@ -1,11 +1,13 @@
 package aptly
 
 import (
+	"encoding/json"
 	"net/http"
 	"testing"
 
 	"github.com/jarcoal/httpmock"
 	"github.com/stretchr/testify/assert"
+	"pault.ag/go/debian/version"
 )
 
 func TestPackagesSearch(t *testing.T) {
@@ -66,14 +68,85 @@ func TestPackagesInfo(t *testing.T) {
 
 	pkg, err := client.PackagesInfo("Pamd64 hello 3.0.0-2 96e8a0deaf8fc95f")
 	assert.NoError(t, err)
+	assert.Equal(t, testPkgsDetailed.Pkgs[0], pkg)
+}
+
+func TestPackageUnmarshalJSON(t *testing.T) {
+	var pkg Package
+	err := json.Unmarshal([]byte(`
+{
+	"Key": "Pamd64 hello 1:3.0.0-2 96e8a0deaf8fc95f",
+	"Package": "hello",
+	"Version": "1:3.0.0-2",
+	"Size": "not a number",
+	"Provides": "hello-world (= 3.0.0), greeter",
+	"Pre-Depends": "dpkg (>= 1.15) | dpkg-fake",
+	"Breaks": "hello-old (<< 3.0~)",
+	"Conflicts": "[broken relation",
+	"X-Custom": "value"
+}
+	`), &pkg)
+	assert.NoError(t, err)
 	assert.Equal(t, Package{
-		Architecture: "amd64",
-		Key:          "Pamd64 hello 3.0.0-2 96e8a0deaf8fc95f",
-		ShortKey:     "Pamd64 hello 3.0.0-2",
-		FilesHash:    "96e8a0deaf8fc95f",
-		Version:      "3.0.0-2",
-		Package:      "hello",
+		Key:        "Pamd64 hello 1:3.0.0-2 96e8a0deaf8fc95f",
+		Package:    "hello",
+		Version:    version.Version{Epoch: 1, Version: "3.0.0", Revision: "2"},
+		Provides:   dep("hello-world (= 3.0.0), greeter"),
+		PreDepends: dep("dpkg (>= 1.15) | dpkg-fake"),
+		Breaks:     dep("hello-old (<< 3.0~)"),
+		Extras: map[string]string{
+			"Size":      "not a number",
+			"Conflicts": "[broken relation",
+			"X-Custom":  "value",
+		},
 	}, pkg)
+	assert.Equal(t, "greeter", pkg.Provides.Relations[1].Possibilities[0].Name)
+
+	err = json.Unmarshal([]byte(`{"Package": "hello", "Version": "a:1.0"}`), &pkg)
+	assert.NoError(t, err)
+	assert.Equal(t, Package{Package: "hello", Extras: map[string]string{"Version": "a:1.0"}}, pkg)
+
+	// the raw version is kept when encoding again
+	data, err := json.Marshal(pkg)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"Package": "hello", "Version": "a:1.0"}`, string(data))
+}
+
+func TestPackageMarshalJSON(t *testing.T) {
+	// round trip keeps all fields
+	for _, pkg := range testPkgsDetailed.Pkgs {
+		data, err := json.Marshal(pkg)
+		assert.NoError(t, err)
+
+		var decoded Package
+		assert.NoError(t, json.Unmarshal(data, &decoded))
+		assert.Equal(t, pkg, decoded)
+	}
+
+	data, err := json.Marshal(testPkgsDetailed.Pkgs[0])
+	assert.NoError(t, err)
+	assert.JSONEq(t, `
+{
+	"Architecture": "amd64",
+	"Depends": "libc6 (>= 2.34)",
+	"Description": " John's hello package\n John's package is written in C\n and prints a greeting.\n .\n It is awesome.\n",
+	"Filename": "hello_3.0.0-2_amd64.deb",
+	"FilesHash": "96e8a0deaf8fc95f",
+	"Installed-Size": "23",
+	"Key": "Pamd64 hello 3.0.0-2 96e8a0deaf8fc95f",
+	"MD5sum": "be7cbf8cf38633a26b73c4511b2d597e",
+	"Maintainer": "John Doe <john@doe.com>",
+	"Package": "hello",
+	"Priority": "optional",
+	"SHA1": "3a4c46b150d3cbe8adb27c44b5b12cca3fd63668",
+	"SHA256": "52417f0e39865af616b69514bb475a2b79d3c06b02d965236e3a1e66a035cc72",
+	"SHA512": "a0fc5403436286c64a8e55a885d5ca1b0ac43407550ad19a012b9cecbcae14327a8d42975672cf7f6f957e2ae812dd2159862ae143beb7982bdf698a0109bade",
+	"Section": "devel",
+	"ShortKey": "Pamd64 hello 3.0.0-2",
+	"Size": "2648",
+	"Version": "3.0.0-2"
+}
+	`, string(data))
 }
 
 func TestPackageFromKey(t *testing.T) {
@@ -84,7 +157,7 @@ func TestPackageFromKey(t *testing.T) {
 		Key:          "Pamd64 hello 3.0.0-2 96e8a0deaf8fc95f",
 		Architecture: "amd64",
 		FilesHash:    "96e8a0deaf8fc95f",
-		Version:      "3.0.0-2",
+		Version:      ver("3.0.0-2"),
 		Package:      "hello",
 	}, pkg)
 
@@ -95,7 +168,7 @@ func TestPackageFromKey(t *testing.T) {
 		Key:          "xDPamd64 hello 3.0.0-2 96e8a0deaf8fc95f",
 		Architecture: "amd64",
 		FilesHash:    "96e8a0deaf8fc95f",
-		Version:      "3.0.0-2",
+		Version:      ver("3.0.0-2"),
 		Package:      "hello",
 	}, pkg)
 
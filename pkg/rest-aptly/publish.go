@@ -1,7 +1,11 @@
 package aptly
 
 import (
+	"errors"
+	"fmt"
 	"strings"
+
+	"github.com/go-resty/resty/v2"
 )
 
 type SourceEntry struct {
@@ -21,7 +25,6 @@ const (
 )
 
 type PublishedList struct {
-	//AcquireByHash bool `json:"acquireByHash,omitempty"`
 	// which architectures are published
 	Architectures []string
 	Distribution  string
@@ -32,6 +35,15 @@ type PublishedList struct {
 	// local or snapshot
 	SourceKind string
 	Sources    []SourceEntry
+	// storage endpoint like s3:bucket, empty for the local filesystem of the server
+	Storage string
+	// "yes" if apt should not upgrade from the repository without user consent
+	NotAutomatic         string
+	ButAutomaticUpgrades string
+	AcquireByHash        bool
+	SkipContents         bool
+	SkipBz2              bool
+	MultiDist            bool
 }
 
 type PublishDropOptions struct {
@@ -49,10 +61,81 @@ func escapePrefix(prefix string) string {
 	return s2
 }
 
+// PublishTarget is where a repository is published, a prefix on a storage endpoint
+//
+// all publish methods accept `[<storage>:]<prefix>` as prefix, e.g. s3:bucket:debian
+type PublishTarget struct {
+	// storage endpoint like s3:bucket, swift:name, azure:name or filesystem:name,
+	// empty for the default storage of the server
+	Storage string
+	// prefix on the storage, "." for the root
+	Prefix string
+}
+
+// ParsePublishTarget parse `[<storage>:]<prefix>` like aptly, the storage is everything before the last colon
+func ParsePublishTarget(target string) PublishTarget {
+	var t PublishTarget
+	if i := strings.LastIndex(target, ":"); i != -1 {
+		t.Storage = target[:i]
+		t.Prefix = target[i+1:]
+	} else {
+		t.Prefix = target
+	}
+
+	t.Prefix = strings.Trim(t.Prefix, "/")
+	if t.Prefix == "" {
+		t.Prefix = "."
+	}
+	return t
+}
+
+// String format as `[<storage>:]<prefix>`
+func (t PublishTarget) String() string {
+	prefix := t.Prefix
+	if prefix == "" {
+		prefix = "."
+	}
+	if t.Storage == "" {
+		return prefix
+	}
+	return t.Storage + ":" + prefix
+}
+
+// escape for use in api urls
+func (t PublishTarget) escape() string {
+	return escapePrefix(t.String())
+}
+
+// Target where the repository is published
+func (l PublishedList) Target() PublishTarget {
+	return PublishTarget{Storage: l.Storage, Prefix: l.Prefix}
+}
+
+// PublishOptions options for publishing repositories and snapshots, unset fields use the server defaults
 type PublishOptions struct {
 	Architectures []string
 	Distribution  *string
 	Component     *string
+	// Value of Label: field in published repository stanza
+	Label *string
+	// Value of Origin: field in published repository stanza
+	Origin *string
+	// overwrite files in pool/ directory without notice
+	ForceOverwrite *bool
+	// setting to "yes" indicates to the package manager to not install or upgrade packages from the repository without user consent
+	NotAutomatic *string
+	// setting to "yes" excludes upgrades from the NotAutomatic setting
+	ButAutomaticUpgrades *string
+	// don't generate contents indexes
+	SkipContents *bool
+	// don't remove unreferenced files in prefix/component
+	SkipCleanup *bool
+	// skip bz2 compression for index files
+	SkipBz2 *bool
+	// provide index files by hash
+	AcquireByHash *bool
+	// enable multiple packages with the same filename in different distributions
+	MultiDist *bool
 }
 
 type PublishSigningOptions struct {
@@ -86,30 +169,54 @@ type publishedRepoCreateParams struct {
 	Sources    []SourceEntryRequest `json:"Sources"`
 	// Distribution name, if missing Aptly would try to guess from sources
 	Distribution *string `json:"Distribution,omitempty"`
-	// // Value of Label: field in published repository stanza
-	// Label optional.Option[string] `json:"Label"`
-	// // Value of Origin: field in published repository stanza
-	// //Origin optional.Option[string] `json:"Origin"`
-	// // when publishing, overwrite files in pool/ directory without notice
-	// ForceOverwrite bool `json:"ForceOverwrite"`
+	// Value of Label: field in published repository stanza
+	Label *string `json:"Label,omitempty"`
+	// Value of Origin: field in published repository stanza
+	Origin *string `json:"Origin,omitempty"`
+	// when publishing, overwrite files in pool/ directory without notice
+	ForceOverwrite *bool `json:"ForceOverwrite,omitempty"`
 	// Override list of published architectures
 	Architectures []string `json:"Architectures,omitempty"`
 	// GPG options
 	Signing PublishSigningOptions `json:"Signing"`
-	// // Setting to yes indicates to the package manager to not install or upgrade packages from the repository without user consent
-	// NotAutomatic string `json:"NotAutomatic"`
-	// // setting to yes excludes upgrades from the NotAutomic setting
-	// ButAutomaticUpgrades string `json:"ButAutomaticUpgrades"`
-	// // Don't generate contents indexes
-	// SkipContents optional.Option[bool] `json:"SkipContents"`
-	// // Don't remove unreferenced files in prefix/component
-	// SkipCleanup optional.Option[bool] `json:"SkipCleanup"`
-	// // Skip bz2 compression for index files
-	// SkipBz2 optional.Option[bool] `json:"SkipBz2"`
-	// // Provide index files by hash
-	// AcquireByHash optional.Option[bool] `json:"AcquireByHash"`
-	// // Enable multiple packages with the same filename in different distributions
-	// MultiDist optional.Option[bool] `json:"MultiDist"`
+	// Setting to yes indicates to the package manager to not install or upgrade packages from the repository without user consent
+	NotAutomatic *string `json:"NotAutomatic,omitempty"`
+	// setting to yes excludes upgrades from the NotAutomic setting
+	ButAutomaticUpgrades *string `json:"ButAutomaticUpgrades,omitempty"`
+	// Don't generate contents indexes
+	SkipContents *bool `json:"SkipContents,omitempty"`
+	// Don't remove unreferenced files in prefix/component
+	SkipCleanup *bool `json:"SkipCleanup,omitempty"`
+	// Skip bz2 compression for index files
+	SkipBz2 *bool `json:"SkipBz2,omitempty"`
+	// Provide index files by hash
+	AcquireByHash *bool `json:"AcquireByHash,omitempty"`
+	// Enable multiple packages with the same filename in different distributions
+	MultiDist *bool `json:"MultiDist,omitempty"`
+}
+
+func newPublishParams(kind string, sources []SourceEntryRequest, opts PublishOptions, sign PublishSigningOptions) publishedRepoCreateParams {
+	params := publishedRepoCreateParams{
+		SourceKind:           kind,
+		Sources:              sources,
+		Distribution:         opts.Distribution,
+		Label:                opts.Label,
+		Origin:               opts.Origin,
+		ForceOverwrite:       opts.ForceOverwrite,
+		Architectures:        opts.Architectures,
+		Signing:              sign,
+		NotAutomatic:         opts.NotAutomatic,
+		ButAutomaticUpgrades: opts.ButAutomaticUpgrades,
+		SkipContents:         opts.SkipContents,
+		SkipCleanup:          opts.SkipCleanup,
+		SkipBz2:              opts.SkipBz2,
+		AcquireByHash:        opts.AcquireByHash,
+		MultiDist:            opts.MultiDist,
+	}
+	// workaround for older aptly versions
+	params.Signing.Batch = true
+
+	return params
 }
 
 func (c *Client) PublishList() ([]PublishedList, error) {
@@ -121,14 +228,24 @@ func (c *Client) PublishList() ([]PublishedList, error) {
 	return lists, c.send(req)
 }
 
+// PublishShow get published repository
+//
+// since Aptly 1.6.0, emulated for older servers
 func (c *Client) PublishShow(distribution string, prefix string) (PublishedList, error) {
 	var lists PublishedList
 
+	fallback, err := c.useFallback("PublishShow", aptly160)
+	if err != nil {
+		return lists, err
+	} else if fallback {
+		return c.publishShowFallback(distribution, prefix)
+	}
+
 	req := c.get("api/publish/{prefix}/{name}").
 		SetResult(&lists).
 		SetPathParams(map[string]string{
 			"name":   distribution,
-			"prefix": escapePrefix(prefix),
+			"prefix": ParsePublishTarget(prefix).escape(),
 		})
 
 	return lists, c.send(req)
@@ -149,66 +266,117 @@ func (c *Client) PublishDrop(name string, prefix string, opts PublishDropOptions
 	req := c.delete("api/publish/{prefix}/{name}").
 		SetPathParams(map[string]string{
 			"name":   name,
-			"prefix": escapePrefix(prefix),
+			"prefix": ParsePublishTarget(prefix).escape(),
 		}).
 		SetQueryParams(params)
 
 	return c.send(req)
 }
 
-func (c *Client) PublishRepo(name string, prefix string, opts PublishOptions, sign PublishSigningOptions) (PublishedList, error) {
+// PublishSources pair components and source names in order, like `-component=main,contrib` of the aptly CLI
+//
+// components may be empty to publish a single source with its default component
+func PublishSources(components []string, names []string) ([]SourceEntryRequest, error) {
+	if len(components) == 0 && len(names) == 1 {
+		return []SourceEntryRequest{{Name: names[0]}}, nil
+	}
+	if len(components) != len(names) {
+		return nil, fmt.Errorf("mismatch in number of components (%d) and sources (%d)", len(components), len(names))
+	}
 
-	reqBody := publishedRepoCreateParams{
-		SourceKind: SourceLocalRepo,
-		Sources: []SourceEntryRequest{
-			{Name: name, Component: opts.Component},
-		},
-		Architectures: opts.Architectures,
-		Distribution:  opts.Distribution,
-		Signing:       sign,
+	sources := make([]SourceEntryRequest, 0, len(names))
+	for i, name := range names {
+		sources = append(sources, SourceEntryRequest{Name: name, Component: &components[i]})
 	}
-	// workaround for older aptly versions
-	reqBody.Signing.Batch = true
+	return sources, nil
+}
+
+// validatePublishSources check that multiple sources have distinct components
+func validatePublishSources(sources []SourceEntryRequest) error {
+	if len(sources) == 0 {
+		return errors.New("minimum one source is required")
+	}
+	if len(sources) == 1 {
+		return nil
+	}
+
+	components := make(map[string]struct{})
+	for _, src := range sources {
+		if src.Component == nil || *src.Component == "" {
+			return fmt.Errorf("component required for source '%s' when publishing multiple sources", src.Name)
+		}
+		if _, ok := components[*src.Component]; ok {
+			return fmt.Errorf("duplicate component '%s'", *src.Component)
+		}
+		components[*src.Component] = struct{}{}
+	}
+	return nil
+}
 
+func (c *Client) PublishRepo(name string, prefix string, opts PublishOptions, sign PublishSigningOptions) (PublishedList, error) {
+	return c.PublishRepos([]SourceEntryRequest{{Name: name, Component: opts.Component}}, prefix, opts, sign)
+}
+
+// PublishRepos publish several local repositories as components of one distribution
+//
+// opts.Component is ignored, every source needs its own component
+func (c *Client) PublishRepos(sources []SourceEntryRequest, prefix string, opts PublishOptions, sign PublishSigningOptions) (PublishedList, error) {
 	var list PublishedList
-	req := c.post("api/publish/{prefix}").
-		SetResult(&list).
-		SetPathParams(map[string]string{
-			"prefix": escapePrefix(prefix),
-		}).
-		SetBody(reqBody)
+
+	req, err := c.publishRequest(SourceLocalRepo, sources, prefix, opts, sign)
+	if err != nil {
+		return list, err
+	}
+	req.SetResult(&list)
 
 	return list, c.send(req)
 }
 
+// PublishRepoAsync like PublishRepo but runs as task on the server
+func (c *Client) PublishRepoAsync(name string, prefix string, opts PublishOptions, sign PublishSigningOptions) (Task, error) {
+	req, err := c.publishRequest(SourceLocalRepo, []SourceEntryRequest{{Name: name, Component: opts.Component}}, prefix, opts, sign)
+	if err != nil {
+		return Task{}, err
+	}
+	return c.sendAsync(req)
+}
+
 func (c *Client) PublishSnapshot(name string, prefix string, opts PublishOptions, sign PublishSigningOptions) (PublishedList, error) {
+	return c.PublishSnapshots([]SourceEntryRequest{{Name: name, Component: opts.Component}}, prefix, opts, sign)
+}
+
+// PublishSnapshots publish several snapshots as components of one distribution
+//
+// opts.Component is ignored, every source needs its own component
+func (c *Client) PublishSnapshots(sources []SourceEntryRequest, prefix string, opts PublishOptions, sign PublishSigningOptions) (PublishedList, error) {
+	var list PublishedList
 
-	reqBody := publishedRepoCreateParams{
-		SourceKind: SourceSnapshot,
-		Sources: []SourceEntryRequest{
-			{Name: name, Component: opts.Component},
-		},
-		Architectures: opts.Architectures,
-		Distribution:  opts.Distribution,
-		Signing:       sign,
+	req, err := c.publishRequest(SourceSnapshot, sources, prefix, opts, sign)
+	if err != nil {
+		return list, err
 	}
-	// workaround for older aptly versions
-	reqBody.Signing.Batch = true
+	req.SetResult(&list)
 
-	var list PublishedList
-	req := c.post("api/publish/{prefix}").
-		SetResult(&list).
+	return list, c.send(req)
+}
+
+func (c *Client) publishRequest(kind string, sources []SourceEntryRequest, prefix string, opts PublishOptions, sign PublishSigningOptions) (*resty.Request, error) {
+	if err := validatePublishSources(sources); err != nil {
+		return nil, err
+	}
+
+	reqBody := newPublishParams(kind, sources, opts, sign)
+
+	return c.post("api/publish/{prefix}").
 		SetPathParams(map[string]string{
-			"prefix": escapePrefix(prefix),
+			"prefix": ParsePublishTarget(prefix).escape(),
 		}).
-		SetBody(reqBody)
-
-	return list, c.send(req)
+		SetBody(reqBody), nil
 }
 
 type PublishUpdateOptions struct {
 	// when publishing, overwrite files in pool/ directory without notice
-	ForceOverwrite bool `json:"ForceOverwrite"`
+	ForceOverwrite *bool `json:"ForceOverwrite,omitempty"`
 	// GPG options
 	Signing PublishSigningOptions `json:"Signing"`
 	// Don't generate contents indexes
@@ -227,18 +395,40 @@ type PublishUpdateOptions struct {
 
 // PublishUpdateOrSwitch updates published list to match repository
 func (c *Client) PublishUpdateOrSwitch(prefix string, distribution string, opts PublishUpdateOptions) (PublishedList, error) {
+	var list PublishedList
+
+	req, err := c.publishUpdateRequest(prefix, distribution, opts)
+	if err != nil {
+		return list, err
+	}
+	req.SetResult(&list)
+
+	return list, c.send(req)
+}
+
+// PublishUpdateOrSwitchAsync like PublishUpdateOrSwitch but runs as task on the server
+func (c *Client) PublishUpdateOrSwitchAsync(prefix string, distribution string, opts PublishUpdateOptions) (Task, error) {
+	req, err := c.publishUpdateRequest(prefix, distribution, opts)
+	if err != nil {
+		return Task{}, err
+	}
+	return c.sendAsync(req)
+}
+
+func (c *Client) publishUpdateRequest(prefix string, distribution string, opts PublishUpdateOptions) (*resty.Request, error) {
+	if len(opts.Snapshots) > 0 {
+		if err := validatePublishSources(opts.Snapshots); err != nil {
+			return nil, err
+		}
+	}
 
 	// workaround for older aptly versions
 	opts.Signing.Batch = true
 
-	var list PublishedList
-	req := c.put("api/publish/{prefix}/{distribution}").
+	return c.put("api/publish/{prefix}/{distribution}").
 		SetPathParams(map[string]string{
-			"prefix":       escapePrefix(prefix),
+			"prefix":       ParsePublishTarget(prefix).escape(),
 			"distribution": escapePrefix(distribution),
 		}).
-		SetBody(opts).
-		SetResult(&list)
-
-	return list, c.send(req)
+		SetBody(opts), nil
 }
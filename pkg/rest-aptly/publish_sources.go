@@ -0,0 +1,129 @@
+package aptly
+
+// Staging changes to the sources of a published repository, since Aptly 1.6.0
+//
+// changes are only published with PublishSourcesApply
+
+func publishSourcesPathParams(prefix string, distribution string) map[string]string {
+	return map[string]string{
+		"prefix":       ParsePublishTarget(prefix).escape(),
+		"distribution": escapePrefix(distribution),
+	}
+}
+
+// PublishSourcesList get the staged sources of a published repository
+func (c *Client) PublishSourcesList(prefix string, distribution string) ([]SourceEntry, error) {
+	var sources []SourceEntry
+
+	if err := c.requireVersion("PublishSourcesList", aptly160); err != nil {
+		return sources, err
+	}
+
+	req := c.get("api/publish/{prefix}/{distribution}/sources").
+		SetPathParams(publishSourcesPathParams(prefix, distribution)).
+		SetResult(&sources)
+
+	return sources, c.send(req)
+}
+
+// PublishSourcesAdd stage a new component
+func (c *Client) PublishSourcesAdd(prefix string, distribution string, source SourceEntry) (PublishedList, error) {
+	var list PublishedList
+
+	if err := c.requireVersion("PublishSourcesAdd", aptly160); err != nil {
+		return list, err
+	}
+
+	req := c.post("api/publish/{prefix}/{distribution}/sources").
+		SetPathParams(publishSourcesPathParams(prefix, distribution)).
+		SetBody(source).
+		SetResult(&list)
+
+	return list, c.send(req)
+}
+
+// PublishSourcesSet stage a replacement of all sources
+func (c *Client) PublishSourcesSet(prefix string, distribution string, sources []SourceEntry) (PublishedList, error) {
+	var list PublishedList
+
+	if err := c.requireVersion("PublishSourcesSet", aptly160); err != nil {
+		return list, err
+	}
+
+	if sources == nil {
+		sources = []SourceEntry{}
+	}
+
+	req := c.put("api/publish/{prefix}/{distribution}/sources").
+		SetPathParams(publishSourcesPathParams(prefix, distribution)).
+		SetBody(sources).
+		SetResult(&list)
+
+	return list, c.send(req)
+}
+
+// PublishSourcesUpdate stage a new source for an existing component
+func (c *Client) PublishSourcesUpdate(prefix string, distribution string, source SourceEntry) (PublishedList, error) {
+	var list PublishedList
+
+	if err := c.requireVersion("PublishSourcesUpdate", aptly160); err != nil {
+		return list, err
+	}
+
+	req := c.put("api/publish/{prefix}/{distribution}/sources/{component}").
+		SetPathParams(publishSourcesPathParams(prefix, distribution)).
+		SetPathParam("component", source.Component).
+		SetBody(source).
+		SetResult(&list)
+
+	return list, c.send(req)
+}
+
+// PublishSourcesRemove stage the removal of a component
+func (c *Client) PublishSourcesRemove(prefix string, distribution string, component string) (PublishedList, error) {
+	var list PublishedList
+
+	if err := c.requireVersion("PublishSourcesRemove", aptly160); err != nil {
+		return list, err
+	}
+
+	req := c.delete("api/publish/{prefix}/{distribution}/sources/{component}").
+		SetPathParams(publishSourcesPathParams(prefix, distribution)).
+		SetPathParam("component", component).
+		SetResult(&list)
+
+	return list, c.send(req)
+}
+
+// PublishSourcesDrop discard all staged changes
+func (c *Client) PublishSourcesDrop(prefix string, distribution string) error {
+	if err := c.requireVersion("PublishSourcesDrop", aptly160); err != nil {
+		return err
+	}
+
+	req := c.delete("api/publish/{prefix}/{distribution}/sources").
+		SetPathParams(publishSourcesPathParams(prefix, distribution))
+
+	return c.send(req)
+}
+
+// PublishSourcesApply publish the staged sources, only signing and the Skip*, AcquireByHash,
+// ForceOverwrite and MultiDist options are used
+func (c *Client) PublishSourcesApply(prefix string, distribution string, opts PublishUpdateOptions) (PublishedList, error) {
+	var list PublishedList
+
+	if err := c.requireVersion("PublishSourcesApply", aptly160); err != nil {
+		return list, err
+	}
+
+	opts.Snapshots = nil
+	// workaround for older aptly versions
+	opts.Signing.Batch = true
+
+	req := c.post("api/publish/{prefix}/{distribution}/update").
+		SetPathParams(publishSourcesPathParams(prefix, distribution)).
+		SetBody(opts).
+		SetResult(&list)
+
+	return list, c.send(req)
+}
@@ -0,0 +1,108 @@
+package aptly
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/maxatome/go-testdeep/td"
+	"github.com/maxatome/tdhttpmock"
+	"github.com/stretchr/testify/assert"
+)
+
+const testStagedPublish = `
+{
+	"Distribution": "bookworm",
+	"Path": "prefix/bookworm",
+	"Prefix": "prefix",
+	"SourceKind": "snapshot",
+	"Sources": [
+		{"Component": "main", "Name": "snapA"}
+	]
+}
+`
+
+func TestPublishSourcesList(t *testing.T) {
+	client := clientForTest(t, "http://host.local")
+
+	httpmock.RegisterResponder(http.MethodGet, "http://host.local/api/publish/prefix/bookworm/sources",
+		newRawJSONResponder(200, `[{"Component": "main", "Name": "snapA-2"}, {"Component": "contrib", "Name": "snapB"}]`))
+
+	sources, err := client.PublishSourcesList("prefix", "bookworm")
+	assert.NoError(t, err)
+	assert.Equal(t, []SourceEntry{
+		{Component: "main", Name: "snapA-2"},
+		{Component: "contrib", Name: "snapB"},
+	}, sources)
+}
+
+func TestPublishSourcesChanges(t *testing.T) {
+	client := clientForTest(t, "http://host.local")
+
+	httpmock.RegisterMatcherResponder(http.MethodPost, "http://host.local/api/publish/prefix/bookworm/sources",
+		tdhttpmock.JSONBody(td.JSON(`{"Component": "contrib", "Name": "snapB"}`)),
+		newRawJSONResponder(201, testStagedPublish))
+	httpmock.RegisterMatcherResponder(http.MethodPut, "http://host.local/api/publish/prefix/bookworm/sources",
+		tdhttpmock.JSONBody(td.JSON(`[{"Component": "main", "Name": "snapA-2"}]`)),
+		newRawJSONResponder(200, testStagedPublish))
+	httpmock.RegisterMatcherResponder(http.MethodPut, "http://host.local/api/publish/prefix/bookworm/sources/main",
+		tdhttpmock.JSONBody(td.JSON(`{"Component": "main", "Name": "snapA-3"}`)),
+		newRawJSONResponder(200, testStagedPublish))
+	httpmock.RegisterResponder(http.MethodDelete, "http://host.local/api/publish/prefix/bookworm/sources/contrib",
+		newRawJSONResponder(200, testStagedPublish))
+	httpmock.RegisterResponder(http.MethodDelete, "http://host.local/api/publish/prefix/bookworm/sources",
+		newRawJSONResponder(200, `{}`))
+
+	published, err := client.PublishSourcesAdd("prefix", "bookworm", SourceEntry{Component: "contrib", Name: "snapB"})
+	assert.NoError(t, err)
+	assert.Equal(t, "prefix/bookworm", published.Path)
+
+	_, err = client.PublishSourcesSet("prefix", "bookworm", []SourceEntry{{Component: "main", Name: "snapA-2"}})
+	assert.NoError(t, err)
+
+	_, err = client.PublishSourcesUpdate("prefix", "bookworm", SourceEntry{Component: "main", Name: "snapA-3"})
+	assert.NoError(t, err)
+
+	_, err = client.PublishSourcesRemove("prefix", "bookworm", "contrib")
+	assert.NoError(t, err)
+
+	assert.NoError(t, client.PublishSourcesDrop("prefix", "bookworm"))
+
+	// version + 5 changes
+	assert.Equal(t, 6, httpmock.GetTotalCallCount())
+}
+
+func TestPublishSourcesApply(t *testing.T) {
+	client := clientForTest(t, "http://host.local")
+
+	httpmock.RegisterMatcherResponder(http.MethodPost, "http://host.local/api/publish/prefix/bookworm/update",
+		httpmock.Matcher{}.And(
+			tdhttpmock.JSONBody(td.JSONPointer("/Signing/Skip", true)),
+			tdhttpmock.JSONBody(td.JSONPointer("/SkipContents", true)),
+			tdhttpmock.JSONBody(td.JSONPointer("/ForceOverwrite", false)),
+			tdhttpmock.JSONBody(td.Not(td.ContainsKey("MultiDist"))),
+			tdhttpmock.JSONBody(td.Not(td.ContainsKey("Snapshots"))),
+		),
+		newRawJSONResponder(200, testStagedPublish))
+
+	published, err := client.PublishSourcesApply("prefix", "bookworm", PublishUpdateOptions{
+		Signing:        WithoutSigning(),
+		SkipContents:   ptr(true),
+		ForceOverwrite: ptr(false),
+		Snapshots:      []SourceEntryRequest{{Name: "ignored"}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []SourceEntry{{Component: "main", Name: "snapA"}}, published.Sources)
+}
+
+func TestPublishSourcesUnsupported(t *testing.T) {
+	client := oldClientForTest(t)
+
+	_, err := client.PublishSourcesList("prefix", "bookworm")
+	assert.ErrorIs(t, err, ErrUnsupportedByServer)
+
+	err = client.PublishSourcesDrop("prefix", "bookworm")
+	assert.EqualError(t, err, "PublishSourcesDrop requires aptly 1.6.0, server version is 1.5.0")
+
+	assert.Equal(t, 1, httpmock.GetTotalCallCount())
+}
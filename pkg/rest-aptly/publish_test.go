@@ -18,6 +18,61 @@ func TestEscapePrefix(t *testing.T) {
 	assert.Equal(t, escapePrefix("."), ":.")
 }
 
+func TestPublishTarget(t *testing.T) {
+	assert.Equal(t, PublishTarget{Prefix: "debian"}, ParsePublishTarget("debian"))
+	assert.Equal(t, PublishTarget{Prefix: "."}, ParsePublishTarget(""))
+	assert.Equal(t, PublishTarget{Prefix: "."}, ParsePublishTarget(":."))
+	assert.Equal(t, PublishTarget{Storage: "s3:bucket", Prefix: "debian/main"}, ParsePublishTarget("s3:bucket:debian/main/"))
+	assert.Equal(t, PublishTarget{Storage: "filesystem:public", Prefix: "."}, ParsePublishTarget("filesystem:public:"))
+
+	assert.Equal(t, "debian", PublishTarget{Prefix: "debian"}.String())
+	assert.Equal(t, ".", PublishTarget{}.String())
+	assert.Equal(t, "s3:bucket:debian", PublishTarget{Storage: "s3:bucket", Prefix: "debian"}.String())
+
+	assert.Equal(t, ":.", PublishTarget{}.escape())
+	assert.Equal(t, ":.", PublishTarget{Prefix: "."}.escape())
+	assert.Equal(t, "part_slug__slug", PublishTarget{Prefix: "part/slug_slug"}.escape())
+	assert.Equal(t, "s3:my__bucket:.", PublishTarget{Storage: "s3:my_bucket"}.escape())
+	assert.Equal(t, "s3:bucket:debian_main", PublishTarget{Storage: "s3:bucket", Prefix: "debian/main"}.escape())
+}
+
+func TestPublishStorage(t *testing.T) {
+	client := clientForTest(t, "http://host.local")
+
+	published := `
+{
+	"Architectures": ["amd64"],
+	"Distribution": "bookworm",
+	"Path": "debian/main/bookworm",
+	"Prefix": "debian/main",
+	"SourceKind": "snapshot",
+	"Sources": [{"Component": "main", "Name": "snap"}],
+	"Storage": "s3:bucket"
+}
+	`
+	httpmock.RegisterResponder(http.MethodGet, "http://host.local/api/publish/s3:bucket:debian_main/bookworm",
+		newRawJSONResponder(200, published))
+	httpmock.RegisterResponder(http.MethodPost, "http://host.local/api/publish/s3:bucket:debian_main",
+		newRawJSONResponder(200, published))
+	httpmock.RegisterResponder(http.MethodPut, "http://host.local/api/publish/s3:bucket:debian_main/bookworm",
+		newRawJSONResponder(200, published))
+	httpmock.RegisterResponder(http.MethodDelete, "http://host.local/api/publish/s3:bucket:debian_main/bookworm",
+		newRawJSONResponder(200, `{}`))
+
+	list, err := client.PublishShow("bookworm", "s3:bucket:debian/main")
+	assert.NoError(t, err)
+	assert.Equal(t, "s3:bucket", list.Storage)
+	assert.Equal(t, PublishTarget{Storage: "s3:bucket", Prefix: "debian/main"}, list.Target())
+
+	_, err = client.PublishSnapshot("snap", "s3:bucket:debian/main", PublishOptions{}, WithoutSigning())
+	assert.NoError(t, err)
+
+	_, err = client.PublishUpdateOrSwitch("s3:bucket:debian/main", "bookworm", PublishUpdateOptions{})
+	assert.NoError(t, err)
+
+	assert.NoError(t, client.PublishDrop("bookworm", "s3:bucket:debian/main", PublishDropOptions{}))
+}
+
 func TestPublishList(t *testing.T) {
 	client := clientForTest(t, "http://host.local")
 
@@ -221,10 +276,188 @@ func TestPublishRepo(t *testing.T) {
 	// TODO more complicated options
 }
 
-// func TestPublishSnapshot(t *testing.T) {
-// 	assert.Fail(t, "TODO")
-// }
+func TestPublishSnapshot(t *testing.T) {
+	client := clientForTest(t, "http://host.local")
+
+	httpmock.RegisterMatcherResponder(http.MethodPost, "http://host.local/api/publish/prefix",
+		httpmock.Matcher{}.And(
+			tdhttpmock.JSONBody(td.JSONPointer("/SourceKind", "snapshot")),
+			tdhttpmock.JSONBody(td.JSONPointer("/Sources/0/Name", "snap")),
+			tdhttpmock.JSONBody(td.JSONPointer("/Sources/0/Component", "contrib")),
+			tdhttpmock.JSONBody(td.JSONPointer("/Architectures", []any{"amd64", "source"})),
+			tdhttpmock.JSONBody(td.JSONPointer("/Label", "Our Label")),
+			tdhttpmock.JSONBody(td.JSONPointer("/Origin", "Our Origin")),
+			tdhttpmock.JSONBody(td.JSONPointer("/NotAutomatic", "yes")),
+			tdhttpmock.JSONBody(td.JSONPointer("/ButAutomaticUpgrades", "yes")),
+			tdhttpmock.JSONBody(td.JSONPointer("/ForceOverwrite", true)),
+			tdhttpmock.JSONBody(td.JSONPointer("/AcquireByHash", true)),
+			tdhttpmock.JSONBody(td.JSONPointer("/SkipContents", false)),
+			tdhttpmock.JSONBody(td.JSONPointer("/Signing/Batch", true)),
+			// unset options are left to the server
+			tdhttpmock.JSONBody(td.Not(td.ContainsKey("SkipBz2"))),
+			tdhttpmock.JSONBody(td.Not(td.ContainsKey("SkipCleanup"))),
+			tdhttpmock.JSONBody(td.Not(td.ContainsKey("MultiDist"))),
+			tdhttpmock.JSONBody(td.Not(td.ContainsKey("Distribution"))),
+		),
+		newRawJSONResponder(200, `
+{
+	"AcquireByHash": true,
+	"Architectures": [
+		"amd64",
+		"source"
+	],
+	"ButAutomaticUpgrades": "yes",
+	"Distribution": "bookworm",
+	"Label": "Our Label",
+	"NotAutomatic": "yes",
+	"Origin": "Our Origin",
+	"Path": "prefix/bookworm",
+	"Prefix": "prefix",
+	"SkipContents": false,
+	"SourceKind": "snapshot",
+	"Sources": [
+		{
+			"Component": "contrib",
+			"Name": "snap"
+		}
+	],
+	"Storage": "",
+	"Suite": ""
+}
+	`))
+
+	published, err := client.PublishSnapshot("snap", "prefix", PublishOptions{
+		Architectures:        []string{"amd64", "source"},
+		Component:            ptr("contrib"),
+		Label:                ptr("Our Label"),
+		Origin:               ptr("Our Origin"),
+		NotAutomatic:         ptr("yes"),
+		ButAutomaticUpgrades: ptr("yes"),
+		ForceOverwrite:       ptr(true),
+		AcquireByHash:        ptr(true),
+		SkipContents:         ptr(false),
+	}, WithoutSigning())
+	assert.NoError(t, err)
+	assert.Equal(t, PublishedList{
+		Architectures:        []string{"amd64", "source"},
+		Distribution:         "bookworm",
+		Label:                "Our Label",
+		Origin:               "Our Origin",
+		Prefix:               "prefix",
+		Path:                 "prefix/bookworm",
+		SourceKind:           "snapshot",
+		Sources:              []SourceEntry{{Name: "snap", Component: "contrib"}},
+		NotAutomatic:         "yes",
+		ButAutomaticUpgrades: "yes",
+		AcquireByHash:        true,
+	}, published)
+}
+
+func TestPublishSources(t *testing.T) {
+	sources, err := PublishSources(nil, []string{"snap"})
+	assert.NoError(t, err)
+	assert.Equal(t, []SourceEntryRequest{{Name: "snap"}}, sources)
+
+	sources, err = PublishSources([]string{"main", "contrib"}, []string{"snapA", "snapB"})
+	assert.NoError(t, err)
+	assert.Equal(t, []SourceEntryRequest{
+		{Name: "snapA", Component: ptr("main")},
+		{Name: "snapB", Component: ptr("contrib")},
+	}, sources)
+
+	_, err = PublishSources([]string{"main"}, []string{"snapA", "snapB"})
+	assert.EqualError(t, err, "mismatch in number of components (1) and sources (2)")
+
+	_, err = PublishSources(nil, []string{"snapA", "snapB"})
+	assert.EqualError(t, err, "mismatch in number of components (0) and sources (2)")
+}
+
+func TestPublishSnapshots(t *testing.T) {
+	client := clientForTest(t, "http://host.local")
+
+	httpmock.RegisterMatcherResponder(http.MethodPost, "http://host.local/api/publish/prefix",
+		httpmock.Matcher{}.And(
+			tdhttpmock.JSONBody(td.JSONPointer("/SourceKind", "snapshot")),
+			tdhttpmock.JSONBody(td.JSONPointer("/Sources", []any{
+				map[string]any{"Component": "main", "Name": "snapA"},
+				map[string]any{"Component": "contrib", "Name": "snapB"},
+			})),
+		),
+		newRawJSONResponder(200, `
+{
+	"Architectures": ["amd64"],
+	"Distribution": "bookworm",
+	"Path": "prefix/bookworm",
+	"Prefix": "prefix",
+	"SourceKind": "snapshot",
+	"Sources": [
+		{"Component": "contrib", "Name": "snapB"},
+		{"Component": "main", "Name": "snapA"}
+	]
+}
+	`))
+
+	sources := []SourceEntryRequest{
+		{Name: "snapA", Component: ptr("main")},
+		{Name: "snapB", Component: ptr("contrib")},
+	}
+	published, err := client.PublishSnapshots(sources, "prefix", PublishOptions{}, WithoutSigning())
+	assert.NoError(t, err)
+	assert.Equal(t, []SourceEntry{{Name: "snapB", Component: "contrib"}, {Name: "snapA", Component: "main"}}, published.Sources)
+
+	// checked before sending
+	_, err = client.PublishSnapshots([]SourceEntryRequest{{Name: "snapA"}, {Name: "snapB", Component: ptr("main")}}, "prefix", PublishOptions{}, WithoutSigning())
+	assert.EqualError(t, err, "component required for source 'snapA' when publishing multiple sources")
+
+	_, err = client.PublishRepos([]SourceEntryRequest{{Name: "a", Component: ptr("main")}, {Name: "b", Component: ptr("main")}}, "prefix", PublishOptions{}, WithoutSigning())
+	assert.EqualError(t, err, "duplicate component 'main'")
 
-// func TestPublishUpdateOrSwitch(t *testing.T) {
-// 	assert.Fail(t, "TODO")
-// }
+	_, err = client.PublishRepos(nil, "prefix", PublishOptions{}, WithoutSigning())
+	assert.EqualError(t, err, "minimum one source is required")
+
+	assert.Equal(t, 1, httpmock.GetTotalCallCount())
+}
+
+func TestPublishUpdateOrSwitch(t *testing.T) {
+	client := clientForTest(t, "http://host.local")
+
+	httpmock.RegisterMatcherResponder(http.MethodPut, "http://host.local/api/publish/prefix/bookworm",
+		httpmock.Matcher{}.And(
+			tdhttpmock.JSONBody(td.JSONPointer("/Snapshots", []any{
+				map[string]any{"Component": "main", "Name": "snapA-2"},
+				map[string]any{"Component": "contrib", "Name": "snapB-2"},
+			})),
+			tdhttpmock.JSONBody(td.JSONPointer("/Signing/Batch", true)),
+		),
+		newRawJSONResponder(200, `
+{
+	"Distribution": "bookworm",
+	"Path": "prefix/bookworm",
+	"Prefix": "prefix",
+	"SourceKind": "snapshot",
+	"Sources": [
+		{"Component": "contrib", "Name": "snapB-2"},
+		{"Component": "main", "Name": "snapA-2"}
+	]
+}
+	`))
+
+	published, err := client.PublishUpdateOrSwitch("prefix", "bookworm", PublishUpdateOptions{
+		Signing: WithoutSigning(),
+		Snapshots: []SourceEntryRequest{
+			{Name: "snapA-2", Component: ptr("main")},
+			{Name: "snapB-2", Component: ptr("contrib")},
+		},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, published.Sources, 2)
+
+	_, err = client.PublishUpdateOrSwitch("prefix", "bookworm", PublishUpdateOptions{
+		Snapshots: []SourceEntryRequest{
+			{Name: "snapA-2", Component: ptr("main")},
+			{Name: "snapB-2"},
+		},
+	})
+	assert.EqualError(t, err, "component required for source 'snapB-2' when publishing multiple sources")
+	assert.Equal(t, 1, httpmock.GetTotalCallCount())
+}
@@ -1,5 +1,7 @@
 package aptly
 
+import "github.com/go-resty/resty/v2"
+
 type LocalRepo struct {
 	Comment             string `json:"comment,omitempty"`
 	DefaultComponent    string `json:"defaultComponent,omitempty"`
@@ -101,9 +103,16 @@ func (c *Client) ReposDrop(name string, force bool) error {
 	return c.send(req)
 }
 
+// RepoAddReport describes the changes made to the repository by an import
+type RepoAddReport struct {
+	Warnings []string `json:"Warnings"`
+	Added    []string `json:"Added"`
+	Removed  []string `json:"Removed"`
+}
+
 type RepoAddResult struct {
-	FailedFiles []string `json:"FailedFiles"`
-	// Report      map[string]string
+	FailedFiles []string      `json:"FailedFiles"`
+	Report      RepoAddReport `json:"Report"`
 }
 
 type RepoAddOptions struct {
@@ -136,7 +145,20 @@ func (c *Client) ReposAddFile(repo string, directory string, filename string, op
 }
 
 func (c *Client) ReposAddDirectory(repo string, directory string, opts RepoAddOptions) (RepoAddResult, error) {
+	var result RepoAddResult
 
+	req := c.reposAddDirectoryRequest(repo, directory, opts).
+		SetResult(&result)
+
+	return result, c.send(req)
+}
+
+// ReposAddDirectoryAsync like ReposAddDirectory but runs as task on the server
+func (c *Client) ReposAddDirectoryAsync(repo string, directory string, opts RepoAddOptions) (Task, error) {
+	return c.sendAsync(c.reposAddDirectoryRequest(repo, directory, opts))
+}
+
+func (c *Client) reposAddDirectoryRequest(repo string, directory string, opts RepoAddOptions) *resty.Request {
 	params := make(map[string]string)
 	if opts.NoRemove {
 		params["noRemove"] = "1"
@@ -145,15 +167,10 @@ func (c *Client) ReposAddDirectory(repo string, directory string, opts RepoAddOp
 		params["forceReplace"] = "1"
 	}
 
-	var result RepoAddResult
-
-	req := c.post("api/repos/{name}/file/{dir}").
+	return c.post("api/repos/{name}/file/{dir}").
 		SetPathParam("name", repo).
 		SetPathParam("dir", directory).
-		SetQueryParams(params).
-		SetResult(&result)
-
-	return result, c.send(req)
+		SetQueryParams(params)
 }
 
 type RepoIncludeOptions struct {
@@ -230,6 +247,20 @@ type pkgRefList struct {
 	PackageRefs []string
 }
 
+// ReposAddPackages add packages already in the database to the repository by key
+func (c *Client) ReposAddPackages(repo string, keys []string) (LocalRepo, error) {
+	refs := pkgRefList{PackageRefs: keys}
+
+	var result LocalRepo
+
+	req := c.post("api/repos/{name}/packages").
+		SetPathParam("name", repo).
+		SetBody(&refs).
+		SetResult(&result)
+
+	return result, c.send(req)
+}
+
 func (c *Client) ReposRemovePackages(repo string, keys []string) (LocalRepo, error) {
 	refs := pkgRefList{PackageRefs: keys}
 
@@ -316,3 +316,48 @@ func TestReposIncludeDirectory(t *testing.T) {
 		assert.Empty(t, res.FailedFiles)
 	})
 }
+
+func TestReposAddRemovePackages(t *testing.T) {
+	client := clientForTest(t, "http://host.local")
+
+	keys := []string{"Pamd64 hello 3.0.0-2 96e8a0deaf8fc95f"}
+	httpmock.RegisterMatcherResponder(http.MethodPost, "http://host.local/api/repos/testRepo/packages",
+		tdhttpmock.JSONBody(td.JSON(`{"PackageRefs": ["Pamd64 hello 3.0.0-2 96e8a0deaf8fc95f"]}`)),
+		newRawJSONResponder(200, `{"Name": "testRepo"}`))
+	httpmock.RegisterMatcherResponder(http.MethodDelete, "http://host.local/api/repos/testRepo/packages",
+		tdhttpmock.JSONBody(td.JSON(`{"PackageRefs": ["Pamd64 hello 3.0.0-2 96e8a0deaf8fc95f"]}`)),
+		newRawJSONResponder(200, `{"Name": "testRepo"}`))
+
+	repo, err := client.ReposAddPackages("testRepo", keys)
+	assert.NoError(t, err)
+	assert.Equal(t, "testRepo", repo.Name)
+
+	repo, err = client.ReposRemovePackages("testRepo", keys)
+	assert.NoError(t, err)
+	assert.Equal(t, "testRepo", repo.Name)
+}
+
+func TestReposAddReport(t *testing.T) {
+	client := clientForTest(t, "http://host.local")
+
+	httpmock.RegisterResponder(http.MethodPost, "http://host.local/api/repos/testRepo/include/dirName", newRawJSONResponder(200, `
+	{
+		"FailedFiles": ["/srv/upload/dirName/broken_1.0_amd64.changes"],
+		"Report": {
+			"Warnings": ["Unable to process /srv/upload/dirName/broken_1.0_amd64.changes: signature verification failed"],
+			"Added": ["hello_1.0_amd64 added"],
+			"Removed": ["hello_0.9_amd64 removed due to conflict with package being added"]
+		}
+	}`))
+
+	res, err := client.ReposIncludeDirectory("testRepo", "dirName", RepoIncludeOptions{ForceReplace: true})
+	assert.NoError(t, err)
+	assert.Equal(t, RepoAddResult{
+		FailedFiles: []string{"/srv/upload/dirName/broken_1.0_amd64.changes"},
+		Report: RepoAddReport{
+			Warnings: []string{"Unable to process /srv/upload/dirName/broken_1.0_amd64.changes: signature verification failed"},
+			Added:    []string{"hello_1.0_amd64 added"},
+			Removed:  []string{"hello_0.9_amd64 removed due to conflict with package being added"},
+		},
+	}, res)
+}
@@ -0,0 +1,101 @@
+package aptly
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// RetryPolicy configures retries of failed requests
+//
+// Requests are retried with exponential backoff and jitter.
+// Non-idempotent requests (POST) are only retried when the server did not process them,
+// that is when the connection could not be established or the error matches ErrorContains
+type RetryPolicy struct {
+	// number of attempts including the first one, values below 2 disable retries
+	MaxAttempts int
+	// wait time before the first retry, doubled for every further retry
+	WaitTime time.Duration
+	// upper limit for the wait time between two attempts
+	MaxWaitTime time.Duration
+	// retry idempotent requests on responses with these status codes
+	StatusCodes []int
+	// retry all requests if the error message contains one of these strings
+	ErrorContains []string
+}
+
+// NewRetryPolicy get retry policy for gateway errors and aptly lock contention
+func NewRetryPolicy(maxAttempts int, waitTime time.Duration) RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: maxAttempts,
+		WaitTime:    waitTime,
+		MaxWaitTime: 30 * time.Second,
+		StatusCodes: []int{
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout,
+		},
+		ErrorContains: []string{
+			"database is locked",
+			"resource temporarily unavailable",
+			"are locked",
+		},
+	}
+}
+
+// SetRetryPolicy enable retries of failed requests, also for the copies of WithContext
+func (c *Client) SetRetryPolicy(policy RetryPolicy) {
+	*c.retry = policy
+	c.client.SetRetryCount(max(policy.MaxAttempts-1, 0)).
+		SetRetryWaitTime(policy.WaitTime).
+		SetRetryMaxWaitTime(policy.MaxWaitTime)
+}
+
+type idempotentKey struct{}
+
+// markIdempotent allow retrying a POST request like GET/PUT/DELETE requests
+func markIdempotent(req *resty.Request) *resty.Request {
+	return req.SetContext(context.WithValue(req.Context(), idempotentKey{}, true))
+}
+
+func isIdempotent(req *resty.Request) bool {
+	marked, _ := req.Context().Value(idempotentKey{}).(bool)
+	return req.Method != resty.MethodPost || marked
+}
+
+// shouldRetry decide if the request is sent again
+func (p *RetryPolicy) shouldRetry(req *resty.Request, resp *resty.Response, err error) bool {
+	if p.MaxAttempts < 2 {
+		return false
+	}
+	if err != nil {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return false
+		}
+		// request never reached the server
+		var opErr *net.OpError
+		if errors.As(err, &opErr) && opErr.Op == "dial" {
+			return true
+		}
+		return isIdempotent(req)
+	}
+	if resp == nil || resp.IsSuccess() {
+		return false
+	}
+
+	if apiErr, ok := resp.Error().(*APIError); ok && apiErr.Valid() {
+		msg := strings.ToLower(*apiErr.ErrorMsg)
+		for _, text := range p.ErrorContains {
+			if strings.Contains(msg, strings.ToLower(text)) {
+				return true
+			}
+		}
+	}
+	return isIdempotent(req) && slices.Contains(p.StatusCodes, resp.StatusCode())
+}
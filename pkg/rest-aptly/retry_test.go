@@ -0,0 +1,121 @@
+package aptly
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func retryClientForTest(t *testing.T) *Client {
+	client := clientForTest(t, "http://host.local")
+	client.SetRetryPolicy(NewRetryPolicy(3, time.Millisecond))
+	return client
+}
+
+func TestRetryIdempotent(t *testing.T) {
+	client := retryClientForTest(t)
+
+	httpmock.RegisterResponder(http.MethodGet, "http://host.local/api/version",
+		httpmock.ResponderFromMultipleResponses([]*http.Response{
+			httpmock.NewStringResponse(503, ""),
+			httpmock.NewStringResponse(502, ""),
+			newRawJSONResponse(200, `{"Version": "1.6.0"}`),
+		}))
+
+	v, err := client.Version()
+	assert.NoError(t, err)
+	assert.Equal(t, "1.6.0", v.Version)
+	assert.Equal(t, 3, httpmock.GetTotalCallCount())
+}
+
+func TestRetryMaxAttempts(t *testing.T) {
+	client := retryClientForTest(t)
+
+	httpmock.RegisterResponder(http.MethodGet, "http://host.local/api/version",
+		httpmock.NewStringResponder(503, ""))
+
+	_, err := client.Version()
+	assert.ErrorContains(t, err, "unexpected response code 503")
+	assert.Equal(t, 3, httpmock.GetTotalCallCount())
+}
+
+func TestRetryPolicyWithContext(t *testing.T) {
+	client := clientForTest(t, "http://host.local")
+	// copies see policies set later
+	bound := client.WithContext(context.Background())
+	client.SetRetryPolicy(NewRetryPolicy(3, time.Millisecond))
+
+	httpmock.RegisterResponder(http.MethodGet, "http://host.local/api/version",
+		httpmock.NewStringResponder(503, ""))
+
+	_, err := bound.Version()
+	assert.ErrorContains(t, err, "unexpected response code 503")
+	assert.Equal(t, 3, httpmock.GetTotalCallCount())
+}
+
+func TestRetryNonIdempotent(t *testing.T) {
+	t.Run("gateway error", func(t *testing.T) {
+		client := retryClientForTest(t)
+
+		httpmock.RegisterResponder(http.MethodPost, "http://host.local/api/repos",
+			httpmock.NewStringResponder(504, ""))
+
+		_, err := client.ReposCreate("repo", RepoCreateOptions{})
+		assert.Error(t, err)
+		assert.Equal(t, 1, httpmock.GetTotalCallCount())
+	})
+
+	t.Run("database locked", func(t *testing.T) {
+		client := retryClientForTest(t)
+
+		httpmock.RegisterResponder(http.MethodPost, "http://host.local/api/repos",
+			httpmock.ResponderFromMultipleResponses([]*http.Response{
+				newRawJSONResponse(500, `{"error": "unable to open database: database is locked"}`),
+				newRawJSONResponse(200, `{"Name": "repo"}`),
+			}))
+
+		repo, err := client.ReposCreate("repo", RepoCreateOptions{})
+		assert.NoError(t, err)
+		assert.Equal(t, "repo", repo.Name)
+		assert.Equal(t, 2, httpmock.GetTotalCallCount())
+	})
+}
+
+func TestRetryFilesUpload(t *testing.T) {
+	client := retryClientForTest(t)
+
+	data := "file0 data"
+	f, err := os.CreateTemp(t.TempDir(), "file0_")
+	assert.NoError(t, err)
+	_, err = f.WriteString(data)
+	assert.NoError(t, err)
+	f.Close()
+
+	attempts := 0
+	httpmock.RegisterResponder(http.MethodPost, "http://host.local/api/files/dirTest",
+		func(req *http.Request) (*http.Response, error) {
+			attempts++
+			if attempts == 1 {
+				return httpmock.NewStringResponse(503, ""), nil
+			}
+			err := req.ParseMultipartForm(1024 * 1024)
+			if err != nil {
+				return nil, err
+			}
+			equal, err := formFileEqual(req, "file0", []byte(data))
+			if err != nil || !equal {
+				return httpmock.NewStringResponse(400, "not equal"), nil
+			}
+			return httpmock.NewJsonResponse(200, []string{"file0"})
+		})
+
+	uploaded, err := client.FilesUpload("dirTest", []string{f.Name()})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"file0"}, uploaded)
+	assert.Equal(t, 2, attempts)
+}
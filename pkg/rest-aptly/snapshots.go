@@ -1,6 +1,10 @@
 package aptly
 
-import "errors"
+import (
+	"errors"
+
+	"github.com/go-resty/resty/v2"
+)
 
 // Snapshot is immutable state of repository: list of packages
 type Snapshot struct {
@@ -10,10 +14,10 @@ type Snapshot struct {
 	CreatedAt  string `json:"CreatedAt"`
 	SourceKind string `json:"SourceKind"`
 	// Sources
-	Snapshots []Snapshot `json:",omitempty"`
-	//RemoteRepos []*RemoteRepo `json:",omitempty"`
-	LocalRepos []LocalRepo `json:",omitempty"`
-	Packages   []string    `json:",omitempty"`
+	Snapshots   []Snapshot   `json:",omitempty"`
+	RemoteRepos []RemoteRepo `json:",omitempty"`
+	LocalRepos  []LocalRepo  `json:",omitempty"`
+	Packages    []string     `json:",omitempty"`
 
 	// Description of how snapshot was created
 	Description string
@@ -215,18 +219,49 @@ type SnapshotMergeOptions struct {
 
 // SnapshotMerge create snapshot by merging many into a single one
 //
-// since aptly 1.6.0
+// since aptly 1.6.0, emulated for older servers
 func (c *Client) SnapshotMerge(destination string, sources []string, opts SnapshotMergeOptions) (Snapshot, error) {
 	var snap Snapshot
+
+	req, err := c.snapshotMergeRequest(destination, sources, opts)
+	if err != nil {
+		return snap, err
+	}
+	fallback, err := c.useFallback("SnapshotMerge", aptly160)
+	if err != nil {
+		return snap, err
+	} else if fallback {
+		return c.snapshotMergeFallback(destination, sources, opts)
+	}
+	req.SetResult(&snap)
+
+	return snap, c.send(req)
+}
+
+// SnapshotMergeAsync like SnapshotMerge but runs as task on the server
+//
+// since aptly 1.6.0
+func (c *Client) SnapshotMergeAsync(destination string, sources []string, opts SnapshotMergeOptions) (Task, error) {
+	req, err := c.snapshotMergeRequest(destination, sources, opts)
+	if err != nil {
+		return Task{}, err
+	}
+	if err := c.requireVersion("SnapshotMergeAsync", aptly160); err != nil {
+		return Task{}, err
+	}
+	return c.sendAsync(req)
+}
+
+func (c *Client) snapshotMergeRequest(destination string, sources []string, opts SnapshotMergeOptions) (*resty.Request, error) {
 	type mergeRequest struct {
 		Sources []string
 	}
 	// check for simple errors before hitting the server
 	if len(sources) == 0 {
-		return snap, errors.New("minimum one source snapshot is required")
+		return nil, errors.New("minimum one source snapshot is required")
 	}
 	if opts.Latest && opts.NoRemove {
-		return snap, errors.New("minimum one source snapshot is required")
+		return nil, errors.New("minimum one source snapshot is required")
 	}
 
 	params := make(map[string]string)
@@ -239,9 +274,8 @@ func (c *Client) SnapshotMerge(destination string, sources []string, opts Snapsh
 
 	req := c.post("api/snapshots/{name}/merge").
 		SetPathParam("name", destination).
-		SetResult(&snap).
 		SetQueryParams(params).
 		SetBody(&mergeRequest{Sources: sources})
 
-	return snap, c.send(req)
+	return req, nil
 }
@@ -202,7 +202,7 @@ func TestSnapshotDiff(t *testing.T) {
 				Key:          "Pamd64 hello 3.0.0-2 96e8a0deaf8fc95f",
 				Architecture: "amd64",
 				Package:      "hello",
-				Version:      "3.0.0-2",
+				Version:      ver("3.0.0-2"),
 				FilesHash:    "96e8a0deaf8fc95f",
 			},
 		},
@@ -211,7 +211,7 @@ func TestSnapshotDiff(t *testing.T) {
 				Key:          "Pamd64 hello-dbgsym 3.0.0-2 185cc47ca86a934c",
 				Architecture: "amd64",
 				Package:      "hello-dbgsym",
-				Version:      "3.0.0-2",
+				Version:      ver("3.0.0-2"),
 				FilesHash:    "185cc47ca86a934c",
 			},
 		},
@@ -220,7 +220,7 @@ func TestSnapshotDiff(t *testing.T) {
 				Key:          "Psource hello 3.0.0-2 571d33f41765ddba",
 				Architecture: "source",
 				Package:      "hello",
-				Version:      "3.0.0-2",
+				Version:      ver("3.0.0-2"),
 				FilesHash:    "571d33f41765ddba",
 			},
 		},
@@ -229,7 +229,7 @@ func TestSnapshotDiff(t *testing.T) {
 				Key:          "Pamd64 nano 7.2-1+deb12u1 c5d2ac1639544e75",
 				Architecture: "amd64",
 				Package:      "nano",
-				Version:      "7.2-1+deb12u1",
+				Version:      ver("7.2-1+deb12u1"),
 				FilesHash:    "c5d2ac1639544e75",
 			},
 		},
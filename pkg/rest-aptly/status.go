@@ -24,6 +24,10 @@ type StorageUsage struct {
 func (c *Client) StorageUsage() (StorageUsage, error) {
 	var storage StorageUsage
 
+	if err := c.requireVersion("StorageUsage", aptly160); err != nil {
+		return storage, err
+	}
+
 	req := c.get("api/storage").
 		SetResult(&storage)
 
@@ -0,0 +1,161 @@
+package aptly
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+)
+
+type TaskState int
+
+const (
+	TaskIdle TaskState = iota
+	TaskRunning
+	TaskSucceeded
+	TaskFailed
+)
+
+func (s TaskState) String() string {
+	switch s {
+	case TaskIdle:
+		return "idle"
+	case TaskRunning:
+		return "running"
+	case TaskSucceeded:
+		return "succeeded"
+	case TaskFailed:
+		return "failed"
+	}
+	return fmt.Sprintf("unknown (%d)", int(s))
+}
+
+// Task is a background operation on the server, created by calls in async mode
+type Task struct {
+	ID    int
+	Name  string
+	State TaskState
+}
+
+// Done true if the task finished, successfully or not
+func (t *Task) Done() bool {
+	return t.State == TaskSucceeded || t.State == TaskFailed
+}
+
+// ErrTaskTimeout is returned by TasksWait when the task did not finish in time
+var ErrTaskTimeout = errors.New("timeout waiting for task")
+
+// default interval used by TasksWait to poll the task state
+const defaultTaskPollInterval = time.Second
+
+// SetTaskPollInterval set interval used by TasksWait to poll the task state
+func (c *Client) SetTaskPollInterval(interval time.Duration) {
+	c.taskPollInterval = interval
+}
+
+// TasksList get list of all tasks
+func (c *Client) TasksList() ([]Task, error) {
+	var tasks []Task
+
+	req := c.get("api/tasks").
+		SetResult(&tasks)
+
+	return tasks, c.send(req)
+}
+
+// TasksShow get task information
+func (c *Client) TasksShow(id int) (Task, error) {
+	var task Task
+
+	req := c.get("api/tasks/{id}").
+		SetPathParam("id", fmt.Sprint(id)).
+		SetResult(&task)
+
+	return task, c.send(req)
+}
+
+// TasksOutput get the log output of the task
+func (c *Client) TasksOutput(id int) (string, error) {
+	var output string
+
+	req := c.get("api/tasks/{id}/output").
+		SetPathParam("id", fmt.Sprint(id)).
+		SetResult(&output)
+
+	return output, c.send(req)
+}
+
+// TasksDetail get details of the task, the content depends on the type of task
+func (c *Client) TasksDetail(id int) (json.RawMessage, error) {
+	var detail json.RawMessage
+
+	req := c.get("api/tasks/{id}/detail").
+		SetPathParam("id", fmt.Sprint(id)).
+		SetResult(&detail)
+
+	return detail, c.send(req)
+}
+
+// TasksWait poll the task until it is done or the timeout expired, a timeout of 0 waits forever
+//
+// Polling is used instead of the blocking wait endpoint so long tasks don't run into proxy timeouts
+func (c *Client) TasksWait(id int, timeout time.Duration) (Task, error) {
+	var deadline time.Time
+	if timeout > 0 {
+		deadline = time.Now().Add(timeout)
+	}
+
+	for {
+		task, err := c.TasksShow(id)
+		if err != nil {
+			return task, err
+		}
+		if task.State == TaskFailed {
+			return task, fmt.Errorf("task %d '%s' failed", task.ID, task.Name)
+		}
+		if task.Done() {
+			return task, nil
+		}
+		wait := c.taskPollInterval
+		if !deadline.IsZero() {
+			remaining := time.Until(deadline)
+			if remaining <= 0 {
+				return task, ErrTaskTimeout
+			}
+			// poll once more at the deadline
+			wait = min(wait, remaining)
+		}
+		select {
+		case <-c.Context().Done():
+			return task, c.Context().Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+// TasksDelete remove finished task from the task list
+func (c *Client) TasksDelete(id int) error {
+	req := c.delete("api/tasks/{id}").
+		SetPathParam("id", fmt.Sprint(id))
+
+	return c.send(req)
+}
+
+// TasksClear remove all finished tasks from the task list
+func (c *Client) TasksClear() error {
+	req := c.post("api/tasks-clear")
+
+	return c.send(req)
+}
+
+// sendAsync send request in async mode and get the created task
+func (c *Client) sendAsync(req *resty.Request) (Task, error) {
+	var task Task
+
+	req.SetQueryParam("_async", "true").
+		SetResult(&task)
+
+	return task, c.send(req)
+}
@@ -0,0 +1,142 @@
+package aptly
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTasksList(t *testing.T) {
+	client := clientForTest(t, "http://host.local")
+
+	httpmock.RegisterResponder(http.MethodGet, "http://host.local/api/tasks",
+		newRawJSONResponder(200, `
+[
+	{"ID": 1, "Name": "Update mirror bookworm", "State": 2},
+	{"ID": 2, "Name": "Publish local repo testing", "State": 1}
+]
+	`))
+
+	tasks, err := client.TasksList()
+	assert.NoError(t, err)
+	assert.Equal(t, []Task{
+		{ID: 1, Name: "Update mirror bookworm", State: TaskSucceeded},
+		{ID: 2, Name: "Publish local repo testing", State: TaskRunning},
+	}, tasks)
+}
+
+func TestTasksShow(t *testing.T) {
+	client := clientForTest(t, "http://host.local")
+
+	httpmock.RegisterResponder(http.MethodGet, "http://host.local/api/tasks/3",
+		newRawJSONResponder(200, `{"ID": 3, "Name": "Merge snapshots", "State": 3}`))
+
+	task, err := client.TasksShow(3)
+	assert.NoError(t, err)
+	assert.Equal(t, Task{ID: 3, Name: "Merge snapshots", State: TaskFailed}, task)
+	assert.True(t, task.Done())
+}
+
+func TestTasksOutput(t *testing.T) {
+	client := clientForTest(t, "http://host.local")
+
+	httpmock.RegisterResponder(http.MethodGet, "http://host.local/api/tasks/3/output",
+		newRawJSONResponder(200, `"Loading packages...\nGenerating metadata files...\n"`))
+
+	output, err := client.TasksOutput(3)
+	assert.NoError(t, err)
+	assert.Equal(t, "Loading packages...\nGenerating metadata files...\n", output)
+}
+
+func TestTasksDetail(t *testing.T) {
+	client := clientForTest(t, "http://host.local")
+
+	httpmock.RegisterResponder(http.MethodGet, "http://host.local/api/tasks/3/detail",
+		newRawJSONResponder(200, `{"RemainingDownloadSize": 0}`))
+
+	detail, err := client.TasksDetail(3)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"RemainingDownloadSize": 0}`, string(detail))
+}
+
+func TestTasksWait(t *testing.T) {
+	t.Run("finished", func(t *testing.T) {
+		client := clientForTest(t, "http://host.local")
+		client.SetTaskPollInterval(time.Millisecond)
+
+		httpmock.RegisterResponder(http.MethodGet, "http://host.local/api/tasks/4",
+			httpmock.ResponderFromMultipleResponses([]*http.Response{
+				newRawJSONResponse(200, `{"ID": 4, "Name": "Publish", "State": 1}`),
+				newRawJSONResponse(200, `{"ID": 4, "Name": "Publish", "State": 1}`),
+				newRawJSONResponse(200, `{"ID": 4, "Name": "Publish", "State": 2}`),
+			}))
+
+		task, err := client.TasksWait(4, 0)
+		assert.NoError(t, err)
+		assert.Equal(t, TaskSucceeded, task.State)
+		assert.Equal(t, 3, httpmock.GetTotalCallCount())
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		client := clientForTest(t, "http://host.local")
+		client.SetTaskPollInterval(time.Millisecond)
+
+		httpmock.RegisterResponder(http.MethodGet, "http://host.local/api/tasks/5",
+			newRawJSONResponder(200, `{"ID": 5, "Name": "Publish", "State": 3}`))
+
+		task, err := client.TasksWait(5, 0)
+		assert.ErrorContains(t, err, "failed")
+		assert.Equal(t, TaskFailed, task.State)
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		client := clientForTest(t, "http://host.local")
+		client.SetTaskPollInterval(time.Millisecond)
+
+		httpmock.RegisterResponder(http.MethodGet, "http://host.local/api/tasks/6",
+			newRawJSONResponder(200, `{"ID": 6, "Name": "Publish", "State": 1}`))
+
+		_, err := client.TasksWait(6, 10*time.Millisecond)
+		assert.ErrorIs(t, err, ErrTaskTimeout)
+	})
+
+	t.Run("finished before timeout shorter than poll interval", func(t *testing.T) {
+		client := clientForTest(t, "http://host.local")
+		client.SetTaskPollInterval(time.Hour)
+
+		httpmock.RegisterResponder(http.MethodGet, "http://host.local/api/tasks/7",
+			httpmock.ResponderFromMultipleResponses([]*http.Response{
+				newRawJSONResponse(200, `{"ID": 7, "Name": "Publish", "State": 1}`),
+				newRawJSONResponse(200, `{"ID": 7, "Name": "Publish", "State": 2}`),
+			}))
+
+		task, err := client.TasksWait(7, 20*time.Millisecond)
+		assert.NoError(t, err)
+		assert.Equal(t, TaskSucceeded, task.State)
+		assert.Equal(t, 2, httpmock.GetTotalCallCount())
+	})
+}
+
+func TestTasksClear(t *testing.T) {
+	client := clientForTest(t, "http://host.local")
+
+	httpmock.RegisterResponder(http.MethodPost, "http://host.local/api/tasks-clear",
+		httpmock.NewStringResponder(200, "{}").Once())
+
+	assert.NoError(t, client.TasksClear())
+}
+
+func TestAsyncRequest(t *testing.T) {
+	client := clientForTest(t, "http://host.local")
+
+	httpmock.RegisterResponderWithQuery(http.MethodPost, "http://host.local/api/publish/prefix",
+		map[string]string{"_async": "true"},
+		newRawJSONResponder(202, `{"ID": 7, "Name": "Publish local repo testing", "State": 0}`))
+
+	task, err := client.PublishRepoAsync("testing", "prefix", PublishOptions{}, WithoutSigning())
+	assert.NoError(t, err)
+	assert.Equal(t, Task{ID: 7, Name: "Publish local repo testing", State: TaskIdle}, task)
+}
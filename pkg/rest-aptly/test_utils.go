@@ -5,6 +5,8 @@ import (
 	"testing"
 
 	"github.com/jarcoal/httpmock"
+	"pault.ag/go/debian/dependency"
+	"pault.ag/go/debian/version"
 )
 
 func newRawJSONResponse(status int, body string) *http.Response {
@@ -23,6 +25,8 @@ func clientForTest(t *testing.T, base string) *Client {
 	client := NewClient(base)
 	// Get the underlying HTTP Client and set it to Mock
 	httpmock.ActivateNonDefault(client.GetClient().GetClient())
+	// for capability detection, tests can overwrite it
+	httpmock.RegisterResponder(http.MethodGet, base+"/api/version", newRawJSONResponder(200, `{"Version": "1.6.0"}`))
 	return client
 }
 
@@ -30,3 +34,21 @@ func clientForTest(t *testing.T, base string) *Client {
 func ptr[T any](v T) *T {
 	return &v
 }
+
+// helper to parse versions, panics on errors
+func ver(v string) version.Version {
+	parsed, err := version.Parse(v)
+	if err != nil {
+		panic(err)
+	}
+	return parsed
+}
+
+// helper to parse relations, panics on errors
+func dep(d string) *dependency.Dependency {
+	parsed, err := dependency.Parse(d)
+	if err != nil {
+		panic(err)
+	}
+	return parsed
+}
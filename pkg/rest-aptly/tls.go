@@ -0,0 +1,59 @@
+package aptly
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// TLSOptions configures certificates of HTTPS connections
+type TLSOptions struct {
+	// PEM file with the CA certificates to verify the server, replaces the system roots, system roots if empty
+	CACert string
+	// PEM file with the client certificate for mutual TLS
+	ClientCert string
+	// PEM file with the private key of the client certificate, the key is read from ClientCert if empty
+	ClientKey string
+	// do not verify the server certificate
+	Insecure bool
+}
+
+// NewTLSConfig get TLS configuration with the certificates of opts loaded
+func NewTLSConfig(opts TLSOptions) (*tls.Config, error) {
+	conf := &tls.Config{InsecureSkipVerify: opts.Insecure}
+
+	if opts.CACert != "" {
+		pem, err := os.ReadFile(opts.CACert)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in '%s'", opts.CACert)
+		}
+		conf.RootCAs = pool
+	}
+
+	if opts.ClientCert != "" {
+		keyFile := opts.ClientKey
+		if keyFile == "" {
+			keyFile = opts.ClientCert
+		}
+		cert, err := tls.LoadX509KeyPair(opts.ClientCert, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("loading client certificate: %w", err)
+		}
+		conf.Certificates = []tls.Certificate{cert}
+	} else if opts.ClientKey != "" {
+		return nil, errors.New("client key given without client certificate")
+	}
+
+	return conf, nil
+}
+
+// SetTLSConfig use conf for HTTPS connections, see NewTLSConfig
+func (c *Client) SetTLSConfig(conf *tls.Config) {
+	c.client.SetTLSClientConfig(conf)
+}
@@ -0,0 +1,123 @@
+package aptly
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"log"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeClientCert create a self-signed client certificate, returns the certificate and paths of the PEM files
+func writeClientCert(t *testing.T, dir string) (*x509.Certificate, string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "raptly"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	keyDer, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+
+	certFile := filepath.Join(dir, "client.crt")
+	keyFile := filepath.Join(dir, "client.key")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDer}), 0o600))
+	return cert, certFile, keyFile
+}
+
+func TestNewTLSConfig(t *testing.T) {
+	dir := t.TempDir()
+	_, certFile, keyFile := writeClientCert(t, dir)
+
+	conf, err := NewTLSConfig(TLSOptions{ClientCert: certFile, ClientKey: keyFile, Insecure: true})
+	assert.NoError(t, err)
+	assert.Len(t, conf.Certificates, 1)
+	assert.True(t, conf.InsecureSkipVerify)
+	assert.Nil(t, conf.RootCAs)
+
+	_, err = NewTLSConfig(TLSOptions{ClientKey: keyFile})
+	assert.EqualError(t, err, "client key given without client certificate")
+
+	_, err = NewTLSConfig(TLSOptions{ClientCert: certFile})
+	assert.ErrorContains(t, err, "loading client certificate")
+
+	_, err = NewTLSConfig(TLSOptions{CACert: keyFile})
+	assert.EqualError(t, err, "no certificates found in '"+keyFile+"'")
+
+	_, err = NewTLSConfig(TLSOptions{CACert: filepath.Join(dir, "missing.crt")})
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestClientMutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	clientCert, certFile, keyFile := writeClientCert(t, dir)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"Version": "1.6.0"}`))
+	}))
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(clientCert)
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+	// rejected handshakes are expected
+	server.Config.ErrorLog = log.New(io.Discard, "", 0)
+	server.StartTLS()
+	defer server.Close()
+
+	caFile := filepath.Join(dir, "ca.crt")
+	require.NoError(t, os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0o600))
+
+	t.Run("with CA and client certificate", func(t *testing.T) {
+		conf, err := NewTLSConfig(TLSOptions{CACert: caFile, ClientCert: certFile, ClientKey: keyFile})
+		require.NoError(t, err)
+		client := NewClient(server.URL)
+		client.SetTLSConfig(conf)
+
+		version, err := client.Version()
+		assert.NoError(t, err)
+		assert.Equal(t, "1.6.0", version.Version)
+	})
+	t.Run("without client certificate", func(t *testing.T) {
+		conf, err := NewTLSConfig(TLSOptions{CACert: caFile})
+		require.NoError(t, err)
+		client := NewClient(server.URL)
+		client.SetTLSConfig(conf)
+
+		_, err = client.Version()
+		assert.Error(t, err)
+	})
+	t.Run("without CA", func(t *testing.T) {
+		conf, err := NewTLSConfig(TLSOptions{ClientCert: certFile, ClientKey: keyFile})
+		require.NoError(t, err)
+		client := NewClient(server.URL)
+		client.SetTLSConfig(conf)
+
+		_, err = client.Version()
+		var unknownAuthority x509.UnknownAuthorityError
+		assert.ErrorAs(t, err, &unknownAuthority)
+	})
+}
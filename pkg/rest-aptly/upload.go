@@ -0,0 +1,323 @@
+package aptly
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// UploadFile is a named reader uploaded by FilesUploadReaders
+type UploadFile struct {
+	// file name on the server
+	Name string
+	// content of the file, only readers implementing io.Seeker can be sent again on retries
+	Reader io.Reader
+	// size in bytes for progress reporting, 0 if unknown
+	Size int64
+}
+
+// UploadProgress is passed to the callback set with SetUploadProgress
+type UploadProgress struct {
+	// name of the file being sent
+	File string
+	// bytes of the file sent so far
+	FileSent int64
+	// size of the file, 0 if unknown
+	FileSize int64
+	// bytes of all files sent so far
+	TotalSent int64
+	// size of all files, sizes of files with unknown size are not included
+	TotalSize int64
+}
+
+// SetUploadProgress set a callback called while uploading files, nil to disable
+//
+// the callback is called from the goroutine sending the request body
+func (c *Client) SetUploadProgress(progress func(UploadProgress)) {
+	c.uploadProgress = progress
+}
+
+var errUploadRestarted = errors.New("upload restarted")
+
+type uploadKey struct{}
+
+// upload streams files as multipart form, the body is recreated for every attempt
+type upload struct {
+	files    []UploadFile
+	progress func(UploadProgress)
+	boundary string
+
+	mu      sync.Mutex
+	attempt int
+	body    *io.PipeReader
+	// closed when the writer of the current attempt returned
+	done chan struct{}
+}
+
+func newUpload(files []UploadFile, progress func(UploadProgress)) *upload {
+	return &upload{
+		files:    files,
+		progress: progress,
+		boundary: multipart.NewWriter(nil).Boundary(),
+	}
+}
+
+// seekable true if all files can be sent again
+func (u *upload) seekable() bool {
+	for _, f := range u.files {
+		if _, ok := f.Reader.(io.Seeker); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// open start sending the files, returns the body and its content type
+func (u *upload) open() (io.Reader, string, error) {
+	u.mu.Lock()
+	body, done := u.body, u.done
+	u.mu.Unlock()
+
+	if body != nil {
+		// stop the previous attempt, the files are only rewound after its writer stopped reading them
+		body.CloseWithError(errUploadRestarted)
+		<-done
+		for _, f := range u.files {
+			seeker, ok := f.Reader.(io.Seeker)
+			if !ok {
+				return nil, "", fmt.Errorf("can not upload '%s' again, reader is not seekable", f.Name)
+			}
+			if _, err := seeker.Seek(0, io.SeekStart); err != nil {
+				return nil, "", err
+			}
+		}
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.attempt++
+
+	body, pw := io.Pipe()
+	writer := multipart.NewWriter(pw)
+	if err := writer.SetBoundary(u.boundary); err != nil {
+		return nil, "", err
+	}
+	u.body = body
+	done = make(chan struct{})
+	u.done = done
+	attempt := u.attempt
+	go func() {
+		defer close(done)
+		pw.CloseWithError(u.write(writer, attempt))
+	}()
+
+	return body, writer.FormDataContentType(), nil
+}
+
+func (u *upload) write(writer *multipart.Writer, attempt int) error {
+	progress := UploadProgress{}
+	for _, f := range u.files {
+		progress.TotalSize += f.Size
+	}
+
+	buf := make([]byte, 32*1024)
+	for i, f := range u.files {
+		part, err := writer.CreateFormFile(fmt.Sprintf("file%d", i), f.Name)
+		if err != nil {
+			return err
+		}
+
+		progress.File = f.Name
+		progress.FileSent = 0
+		progress.FileSize = f.Size
+		u.report(attempt, progress)
+
+		for {
+			n, err := f.Reader.Read(buf)
+			if n > 0 {
+				if _, err := part.Write(buf[:n]); err != nil {
+					return err
+				}
+				progress.FileSent += int64(n)
+				progress.TotalSent += int64(n)
+				u.report(attempt, progress)
+			}
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				return err
+			}
+		}
+	}
+	return writer.Close()
+}
+
+// report progress unless a newer attempt started
+func (u *upload) report(attempt int, progress UploadProgress) {
+	if u.progress == nil {
+		return
+	}
+	u.mu.Lock()
+	current := attempt == u.attempt
+	u.mu.Unlock()
+	if current {
+		u.progress(progress)
+	}
+}
+
+// uploadMiddleware set the streamed body of upload requests before every attempt
+func uploadMiddleware(_ *resty.Client, r *resty.Request) error {
+	u, ok := r.Context().Value(uploadKey{}).(*upload)
+	if !ok {
+		return nil
+	}
+
+	body, contentType, err := u.open()
+	if err != nil {
+		return err
+	}
+	r.SetBody(body).
+		SetHeader("Content-Type", contentType)
+	return nil
+}
+
+// UploadResult is the outcome of one file uploaded by FilesUploadParallel
+type UploadResult struct {
+	// local path of the file
+	File string
+	// files reported by the server
+	Uploaded []string
+	// nil if the upload succeeded
+	Err error
+}
+
+// ParallelUploadOptions configures FilesUploadParallel
+type ParallelUploadOptions struct {
+	// maximum number of concurrent requests, values below 1 upload one file at a time
+	Parallel int
+	// attempts per file including the first one, values below 2 disable retries of failed files
+	//
+	// independent of the RetryPolicy of the client, which applies to every attempt
+	Attempts int
+	// wait time before a failed file is uploaded again
+	WaitTime time.Duration
+}
+
+// FilesUploadParallel upload local files to dir with up to opts.Parallel concurrent requests
+//
+// Every file is sent in its own request, a failure only affects this file and it is uploaded again on its own
+// up to opts.Attempts times. The results are in the order of files, the error reports how many failed.
+// The progress callback is called from several goroutines with the totals of all files.
+func (c *Client) FilesUploadParallel(dir string, files []string, opts ParallelUploadOptions) ([]UploadResult, error) {
+	results := make([]UploadResult, len(files))
+	progress := newParallelProgress(c.uploadProgress, files)
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range max(opts.Parallel, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = c.uploadAttempts(dir, files[i], progress.forFile(i), opts)
+			}
+		}()
+	}
+
+	ctx := c.Context()
+	for i, file := range files {
+		if ctx.Err() != nil {
+			results[i] = UploadResult{File: file, Err: ctx.Err()}
+			continue
+		}
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	failed := 0
+	for _, result := range results {
+		if result.Err != nil {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return results, fmt.Errorf("%d of %d files failed to upload", failed, len(files))
+	}
+	return results, nil
+}
+
+// uploadAttempts upload file until it succeeded, the attempts are used up or the context is done
+func (c *Client) uploadAttempts(dir string, file string, progress func(UploadProgress), opts ParallelUploadOptions) UploadResult {
+	ctx := c.Context()
+	result := c.uploadOne(dir, file, progress)
+	for attempt := 1; attempt < opts.Attempts && result.Err != nil; attempt++ {
+		select {
+		case <-ctx.Done():
+			return result
+		case <-time.After(opts.WaitTime):
+		}
+		result = c.uploadOne(dir, file, progress)
+	}
+	return result
+}
+
+func (c *Client) uploadOne(dir string, file string, progress func(UploadProgress)) UploadResult {
+	result := UploadResult{File: file}
+
+	upload, err := openUploadFile(file)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	defer upload.Reader.(io.Closer).Close()
+
+	result.Uploaded, result.Err = c.filesUpload(dir, []UploadFile{upload}, progress)
+	return result
+}
+
+// parallelProgress sums the progress of concurrent uploads
+type parallelProgress struct {
+	callback  func(UploadProgress)
+	mu        sync.Mutex
+	sent      []int64
+	totalSize int64
+}
+
+func newParallelProgress(callback func(UploadProgress), files []string) *parallelProgress {
+	p := &parallelProgress{callback: callback, sent: make([]int64, len(files))}
+	if callback == nil {
+		return p
+	}
+	for _, file := range files {
+		if fi, err := os.Stat(file); err == nil {
+			p.totalSize += fi.Size()
+		}
+	}
+	return p
+}
+
+// forFile progress callback for the upload of file i, nil if progress is not reported
+func (p *parallelProgress) forFile(i int) func(UploadProgress) {
+	if p.callback == nil {
+		return nil
+	}
+	return func(progress UploadProgress) {
+		p.mu.Lock()
+		p.sent[i] = progress.FileSent
+		progress.TotalSent = 0
+		for _, sent := range p.sent {
+			progress.TotalSent += sent
+		}
+		progress.TotalSize = p.totalSize
+		p.mu.Unlock()
+
+		p.callback(progress)
+	}
+}